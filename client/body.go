@@ -0,0 +1,184 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rip-zoyo/orbit-tls/profiles"
+)
+
+type Multipart struct {
+	parts []multipartPart
+}
+
+type multipartPart struct {
+	name        string
+	value       string
+	filename    string
+	contentType string
+	content     io.Reader
+	path        string
+	file        bool
+}
+
+func NewMultipart() *Multipart {
+	return &Multipart{}
+}
+
+func (m *Multipart) AddField(name, value string) {
+	m.parts = append(m.parts, multipartPart{name: name, value: value})
+}
+
+func (m *Multipart) AddFile(name, filename string, content []byte) {
+	m.AddFileReader(name, filename, "", bytes.NewReader(content))
+}
+
+func (m *Multipart) AddFileReader(name, filename, contentType string, content io.Reader) {
+	m.parts = append(m.parts, multipartPart{
+		name:        name,
+		filename:    filename,
+		contentType: contentType,
+		content:     content,
+		file:        true,
+	})
+}
+
+func (m *Multipart) AddFilePath(name, path string) {
+	m.parts = append(m.parts, multipartPart{
+		name:     name,
+		filename: filepath.Base(path),
+		path:     path,
+		file:     true,
+	})
+}
+
+func (m *Multipart) encode(boundary string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, fmt.Errorf("invalid multipart boundary: %w", err)
+	}
+
+	for _, part := range m.parts {
+		header := make(textproto.MIMEHeader)
+		if !part.file {
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeFormName(part.name)))
+			w, err := writer.CreatePart(header)
+			if err != nil {
+				return nil, fmt.Errorf("failed to write field %s: %w", part.name, err)
+			}
+			io.WriteString(w, part.value)
+			continue
+		}
+
+		contentType := part.contentType
+		if contentType == "" {
+			contentType, _, _ = strings.Cut(mime.TypeByExtension(filepath.Ext(part.filename)), ";")
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeFormName(part.name), escapeFormName(part.filename)))
+		header.Set("Content-Type", contentType)
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write file %s: %w", part.filename, err)
+		}
+		if err := part.copyTo(w); err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", part.filename, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish multipart body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (p multipartPart) copyTo(w io.Writer) error {
+	if p.path == "" {
+		_, err := io.Copy(w, p.content)
+		return err
+	}
+
+	file, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+var formNameEscaper = strings.NewReplacer("\n", "%0A", "\r", "%0D", `"`, "%22")
+
+func escapeFormName(name string) string {
+	return formNameEscaper.Replace(name)
+}
+
+func formBoundary(family string) string {
+	if family == profiles.FamilyFirefox {
+		boundary := "---------------------------"
+		for range 3 {
+			boundary += strconv.FormatUint(uint64(rand.Uint32()), 10)
+		}
+		return boundary
+	}
+
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	suffix := make([]byte, 16)
+	for i := range suffix {
+		suffix[i] = alphabet[rand.IntN(len(alphabet))]
+	}
+	return "----WebKitFormBoundary" + string(suffix)
+}
+
+func (c *Client) encodeBody(body interface{}) (io.Reader, string, error) {
+	switch v := body.(type) {
+	case nil:
+		return nil, "", nil
+	case string:
+		return strings.NewReader(v), "", nil
+	case []byte:
+		return bytes.NewReader(v), "", nil
+	case url.Values:
+		return strings.NewReader(v.Encode()), "application/x-www-form-urlencoded", nil
+	case *Multipart:
+		boundary := formBoundary(c.profile.Family)
+		data, err := v.encode(boundary)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(data), "multipart/form-data; boundary=" + boundary, nil
+	case io.Reader:
+		return v, "", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported body type: %T", body)
+	}
+}
+
+func marshalJSON(body interface{}) (interface{}, error) {
+	switch v := body.(type) {
+	case string, []byte:
+		return v, nil
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type receivedBody struct {
+	contentType   string
+	contentLength int64
+	body          string
+}
+
+func newBodyServer(t *testing.T) (*httptest.Server, chan receivedBody) {
+	t.Helper()
+	received := make(chan receivedBody, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedBody{
+			contentType:   r.Header.Get("Content-Type"),
+			contentLength: r.ContentLength,
+			body:          string(body),
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestMultipartBoundary(t *testing.T) {
+	webkit := regexp.MustCompile(`^----WebKitFormBoundary[A-Za-z0-9]{16}$`)
+	firefox := regexp.MustCompile(`^-{27}[0-9]+$`)
+	tests := []struct {
+		profile string
+		pattern *regexp.Regexp
+	}{
+		{"Chrome138", webkit},
+		{"Edge120", webkit},
+		{"Safari18", webkit},
+		{"Firefox131", firefox},
+		{"MullvadBrowser", firefox},
+	}
+
+	server, received := newBodyServer(t)
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			c, err := New(tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			form := NewMultipart()
+			form.AddField("title", "report")
+			form.AddFile("attachment", "notes.txt", []byte("hello"))
+			if _, err := c.PostMultipart(server.URL, form); err != nil {
+				t.Fatal(err)
+			}
+
+			got := <-received
+			mediaType, params, err := mime.ParseMediaType(got.contentType)
+			if err != nil || mediaType != "multipart/form-data" {
+				t.Fatalf("content type = %q, want multipart/form-data", got.contentType)
+			}
+			boundary := params["boundary"]
+			if !tt.pattern.MatchString(boundary) {
+				t.Errorf("boundary %q does not match %s", boundary, tt.pattern)
+			}
+			if got.contentLength != int64(len(got.body)) {
+				t.Errorf("content length = %d, body is %d bytes", got.contentLength, len(got.body))
+			}
+			if !strings.HasPrefix(got.body, "--"+boundary+"\r\n") || !strings.HasSuffix(got.body, "--"+boundary+"--\r\n") {
+				t.Errorf("body is not delimited by boundary %q", boundary)
+			}
+		})
+	}
+}
+
+func TestMultipartParts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("up%22load")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		io.WriteString(w, strings.Join([]string{
+			r.FormValue("title"), header.Filename, header.Header.Get("Content-Type"), string(content),
+		}, "|"))
+	}))
+	defer server.Close()
+
+	c, err := New("Chrome138")
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := NewMultipart()
+	form.AddField("title", "Quarterly report")
+	form.AddFileReader("up\"load", "data.json", "", strings.NewReader(`{"a":1}`))
+	resp, err := c.PostMultipart(server.URL, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `Quarterly report|data.json|application/json|{"a":1}`; resp.Text != want {
+		t.Errorf("server saw %q, want %q", resp.Text, want)
+	}
+}
+
+func TestFormEncoding(t *testing.T) {
+	server, received := newBodyServer(t)
+	c, err := New("Chrome138")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{
+		"user":  {"a b&c"},
+		"tags":  {"x", "y=z"},
+		"empty": {""},
+	}
+	if _, err := c.PostForm(server.URL, form); err != nil {
+		t.Fatal(err)
+	}
+	got := <-received
+	if got.contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("content type = %q, want application/x-www-form-urlencoded", got.contentType)
+	}
+	if want := "empty=&tags=x&tags=y%3Dz&user=a+b%26c"; got.body != want {
+		t.Errorf("body = %q, want %q", got.body, want)
+	}
+	if got.contentLength != int64(len(got.body)) {
+		t.Errorf("content length = %d, body is %d bytes", got.contentLength, len(got.body))
+	}
+
+	if _, err := c.PostForm(server.URL, form, map[string]string{"Content-Type": "text/plain"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; got.contentType != "text/plain" {
+		t.Errorf("content type = %q, want the caller's text/plain", got.contentType)
+	}
+}
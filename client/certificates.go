@@ -0,0 +1,180 @@
+package client
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type ClientCertificate struct {
+	Hosts       []string
+	CertFile    string
+	KeyFile     string
+	PKCS12File  string
+	Password    string
+	Certificate *tls.Certificate
+}
+
+type clientCertificate struct {
+	hosts []string
+	cert  *tls.Certificate
+}
+
+func LoadPKCS12(data []byte, password string) (tls.Certificate, error) {
+	key, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to decode PKCS#12: %w", err)
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	for _, ca := range chain {
+		cert.Certificate = append(cert.Certificate, ca.Raw)
+	}
+	return cert, nil
+}
+
+func (entry ClientCertificate) load() (tls.Certificate, error) {
+	switch {
+	case entry.Certificate != nil:
+		return *entry.Certificate, nil
+	case entry.PKCS12File != "":
+		data, err := os.ReadFile(entry.PKCS12File)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		return LoadPKCS12(data, entry.Password)
+	case entry.CertFile != "":
+		keyFile := entry.KeyFile
+		if keyFile == "" {
+			keyFile = entry.CertFile
+		}
+		return tls.LoadX509KeyPair(entry.CertFile, keyFile)
+	default:
+		return tls.Certificate{}, errors.New("no certificate configured")
+	}
+}
+
+func loadClientCertificates(entries []ClientCertificate, algorithms []uint16) ([]clientCertificate, error) {
+	schemes := make([]tls.SignatureScheme, len(algorithms))
+	for i, algorithm := range algorithms {
+		schemes[i] = tls.SignatureScheme(algorithm)
+	}
+
+	loaded := make([]clientCertificate, 0, len(entries))
+	for i, entry := range entries {
+		cert, err := entry.load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %d: %w", i, err)
+		}
+		if supported := schemesForKey(cert.PrivateKey, schemes); len(supported) > 0 {
+			cert.SupportedSignatureAlgorithms = supported
+		}
+
+		hosts := make([]string, len(entry.Hosts))
+		for j, host := range entry.Hosts {
+			hosts[j] = strings.ToLower(strings.TrimSuffix(host, "."))
+		}
+		loaded = append(loaded, clientCertificate{hosts: hosts, cert: &cert})
+	}
+	return loaded, nil
+}
+
+func schemesForKey(key crypto.PrivateKey, schemes []tls.SignatureScheme) []tls.SignatureScheme {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil
+	}
+
+	var usable func(tls.SignatureScheme) bool
+	switch public := signer.Public().(type) {
+	case *rsa.PublicKey:
+		usable = func(scheme tls.SignatureScheme) bool {
+			switch scheme {
+			case tls.PKCS1WithSHA256, tls.PKCS1WithSHA384, tls.PKCS1WithSHA512,
+				tls.PSSWithSHA256, tls.PSSWithSHA384, tls.PSSWithSHA512:
+				return true
+			}
+			return false
+		}
+	case *ecdsa.PublicKey:
+		var want tls.SignatureScheme
+		switch public.Curve {
+		case elliptic.P256():
+			want = tls.ECDSAWithP256AndSHA256
+		case elliptic.P384():
+			want = tls.ECDSAWithP384AndSHA384
+		case elliptic.P521():
+			want = tls.ECDSAWithP521AndSHA512
+		}
+		usable = func(scheme tls.SignatureScheme) bool { return scheme == want }
+	case ed25519.PublicKey:
+		usable = func(scheme tls.SignatureScheme) bool { return scheme == tls.Ed25519 }
+	default:
+		return nil
+	}
+
+	var supported []tls.SignatureScheme
+	for _, scheme := range schemes {
+		if usable(scheme) {
+			supported = append(supported, scheme)
+		}
+	}
+	return supported
+}
+
+func matchHost(pattern, host string) bool {
+	if pattern == host {
+		return true
+	}
+	_, parent, found := strings.Cut(host, ".")
+	return found && pattern == "*."+parent
+}
+
+func (c *Client) clientCertificatesFor(host string) []*tls.Certificate {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	var certs []*tls.Certificate
+	for _, entry := range c.clientCerts {
+		if len(entry.hosts) == 0 {
+			certs = append(certs, entry.cert)
+			continue
+		}
+		for _, pattern := range entry.hosts {
+			if matchHost(pattern, host) {
+				certs = append(certs, entry.cert)
+				break
+			}
+		}
+	}
+	return certs
+}
+
+func (c *Client) withClientCertificate(config *tls.Config, host string) *tls.Config {
+	certs := c.clientCertificatesFor(host)
+	if len(certs) == 0 {
+		return config
+	}
+
+	config = config.Clone()
+	config.GetClientCertificate = func(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		for _, cert := range certs {
+			if cri.SupportsCertificate(cert) == nil {
+				return cert, nil
+			}
+		}
+		return &tls.Certificate{}, nil
+	}
+	return config
+}
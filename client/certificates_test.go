@@ -0,0 +1,174 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, key
+}
+
+func newTestClientCertificate(t *testing.T, name string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestMutualTLS(t *testing.T) {
+	trusted, trustedKey := newTestCA(t, "trusted")
+	other, otherKey := newTestCA(t, "other")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(trusted)
+
+	tests := []struct {
+		name       string
+		clientAuth tls.ClientAuthType
+		certs      []*tls.Certificate
+		want       string
+	}{
+		{"accepted CA", tls.RequireAndVerifyClientCert, []*tls.Certificate{
+			newTestClientCertificate(t, "wrong", other, otherKey),
+			newTestClientCertificate(t, "right", trusted, trustedKey),
+		}, "right"},
+		{"no acceptable certificate", tls.VerifyClientCertIfGiven, []*tls.Certificate{
+			newTestClientCertificate(t, "wrong", other, otherKey),
+		}, "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if len(r.TLS.PeerCertificates) == 0 {
+					w.Write([]byte("none"))
+					return
+				}
+				w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+			}))
+			server.TLS = &tls.Config{ClientAuth: tt.clientAuth, ClientCAs: clientCAs}
+			server.StartTLS()
+			defer server.Close()
+			roots := x509.NewCertPool()
+			roots.AddCert(server.Certificate())
+
+			var entries []ClientCertificate
+			for _, cert := range tt.certs {
+				entries = append(entries, ClientCertificate{Certificate: cert})
+			}
+			c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots, ClientCertificates: entries})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Text != tt.want {
+				t.Errorf("server saw client certificate %q, want %q", resp.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCertificateHosts(t *testing.T) {
+	exact, wildcard, fallback := &tls.Certificate{}, &tls.Certificate{}, &tls.Certificate{}
+	c := &Client{clientCerts: []clientCertificate{
+		{hosts: []string{"api.example.com"}, cert: exact},
+		{hosts: []string{"*.example.com"}, cert: wildcard},
+		{cert: fallback},
+	}}
+
+	tests := []struct {
+		host string
+		want []*tls.Certificate
+	}{
+		{"api.example.com", []*tls.Certificate{exact, wildcard, fallback}},
+		{"www.example.com.", []*tls.Certificate{wildcard, fallback}},
+		{"a.b.example.com", []*tls.Certificate{fallback}},
+		{"example.com", []*tls.Certificate{fallback}},
+	}
+	for _, tt := range tests {
+		if got := c.clientCertificatesFor(tt.host); !slices.Equal(got, tt.want) {
+			t.Errorf("clientCertificatesFor(%q) returned %d certificates, want %d", tt.host, len(got), len(tt.want))
+		}
+	}
+}
+
+func TestSchemesForKey(t *testing.T) {
+	profile := []tls.SignatureScheme{
+		tls.ECDSAWithP256AndSHA256, tls.PSSWithSHA256, tls.PKCS1WithSHA256,
+		tls.ECDSAWithP384AndSHA384, tls.PSSWithSHA384, tls.PKCS1WithSHA384,
+		tls.PSSWithSHA512, tls.PKCS1WithSHA512, tls.Ed25519,
+	}
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name string
+		key  interface{}
+		want []tls.SignatureScheme
+	}{
+		{"RSA", rsaKey, []tls.SignatureScheme{
+			tls.PSSWithSHA256, tls.PKCS1WithSHA256, tls.PSSWithSHA384,
+			tls.PKCS1WithSHA384, tls.PSSWithSHA512, tls.PKCS1WithSHA512,
+		}},
+		{"P-384", p384Key, []tls.SignatureScheme{tls.ECDSAWithP384AndSHA384}},
+		{"Ed25519", edKey, []tls.SignatureScheme{tls.Ed25519}},
+	}
+	for _, tt := range tests {
+		if got := schemesForKey(tt.key, profile); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: schemes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,15 +1,19 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rip-zoyo/orbit-tls/fingerprint"
@@ -20,29 +24,83 @@ import (
 type Client struct {
 	httpClient      *http.Client
 	profile         *profiles.Profile
+	options         ClientOptions
+	transport       *http.Transport
+	tlsConfig       *tls.Config
+	dialer          *tracking.TrackedDialer
+	netDialer       *net.Dialer
+	resolver        *resolver
+	pins            map[string][]string
+	clientCerts     []clientCertificate
+	connections     *connectionPool
 	headers         *OrderedHeaders
 	tracker         *tracking.TLSTracker
 	http2Tracker    *tracking.HTTP2Tracker
 	lastFingerprint *fingerprint.Data
+	fingerprintMu   sync.RWMutex
+	echMu           sync.Mutex
+	echConfigs      map[string]echEntry
+	clientHints     *clientHintStore
+	middleware      *middlewareTransport
+}
+
+type ClientOptions struct {
+	ECHConfigList            []byte
+	ECHResolver              string
+	SessionCacheSize         int
+	DisableSessionResumption bool
+	HTTP3                    bool
+	EarlyData                bool
+	Protocol                 ProtocolMode
+	OS                       string
+	Locales                  []string
+	Hosts                    map[string]string
+	DNSResolver              string
+	IPPreference             IPPreference
+	Pins                     map[string][]string
+	RootCAs                  *x509.CertPool
+	InsecureSkipVerify       bool
+	VerifyConnection         func(state tls.ConnectionState, details *tracking.ConnectionDetails) error
+	ClientCertificates       []ClientCertificate
+	Proxy                    func(*http.Request) (*url.URL, error)
 }
 
 type Response struct {
 	*http.Response
 	Text        string           `json:"text"`
+	URL         string           `json:"url"`
+	Elapsed     time.Duration    `json:"elapsed"`
+	Timings     Timings          `json:"timings"`
 	Fingerprint *fingerprint.Data `json:"fingerprint,omitempty"`
+	body        []byte
 }
 
 type RequestOptions struct {
-	Headers           map[string]string
-	HeadersSlice      [][]string
-	HeadersStringList []string
-	HeadersJSON       string
-	Cookies           []*http.Cookie
-	Params            map[string]string
-	Timeout           *int
+	Headers            map[string]string
+	HeadersSlice       [][]string
+	HeadersStringList  []string
+	HeadersJSON        string
+	Cookies            []*http.Cookie
+	Params             map[string]string
+	Timeout            *int
+	Intent             RequestIntent
+	Referrer           string
+	NoUserActivation   bool
+	HeaderMode         HeaderMode
+	RemoveHeaders      []string
+	ForceNewConnection bool
 }
 
+type HeaderMode int
+
+const (
+	HeaderMerge HeaderMode = iota
+	HeaderReplace
+	HeaderProfileOnly
+)
+
 type OrderedHeaders struct {
+	mu      sync.RWMutex
 	headers []header
 }
 
@@ -51,6 +109,8 @@ type header struct {
 	value string
 }
 
+const defaultSessionCacheSize = 64
+
 var Chrome120 *Client
 var Chrome131 *Client
 var Chrome138 *Client
@@ -145,63 +205,219 @@ func init() {
 }
 
 func New(profileName string) (*Client, error) {
-	profile, err := profiles.Get(profileName)
+	return NewWithOptions(profileName, nil)
+}
+
+func NewWithOptions(profileName string, options *ClientOptions) (*Client, error) {
+	var opts ClientOptions
+	if options != nil {
+		opts = *options
+	}
+
+	var profileOpts []profiles.Option
+	if opts.OS != "" {
+		profileOpts = append(profileOpts, profiles.OS(opts.OS))
+	}
+	if len(opts.Locales) > 0 {
+		profileOpts = append(profileOpts, profiles.Locales(opts.Locales...))
+	}
+
+	profile, err := profiles.Get(profileName, profileOpts...)
 	if err != nil {
 		return nil, err
 	}
 
+	if opts.EarlyData && !opts.HTTP3 {
+		return nil, errors.New("early data requires HTTP3: TLS over TCP does not send 0-RTT data")
+	}
+	if opts.HTTP3 && opts.Proxy != nil {
+		return nil, errors.New("proxy cannot be combined with HTTP3: QUIC datagrams would bypass the proxy")
+	}
+
 	tlsConfig := &tls.Config{
 		MinVersion:         profile.TLSVersion.Min,
 		MaxVersion:         profile.TLSVersion.Max,
-		InsecureSkipVerify: false,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		RootCAs:            opts.RootCAs,
 		CipherSuites:       profile.CipherSuites,
 		CurvePreferences:   profile.CurvePreferences,
-		NextProtos:         profile.ALPNProtocols,
+		NextProtos:         opts.Protocol.alpn(profile.ALPNProtocols),
 	}
 
-	trackedDialer := tracking.NewTrackedDialer()
+	if opts.HTTP3 && !opts.DisableSessionResumption {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(cacheSizeFor(opts))
+	}
+
+	client := &Client{
+		profile:      profile,
+		options:      opts,
+		tlsConfig:    tlsConfig,
+		dialer:       tracking.NewTrackedDialer(),
+		netDialer:    &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		connections:  newConnectionPool(),
+		headers:      NewOrderedHeaders(),
+		tracker:      tracking.NewTLSTracker(),
+		http2Tracker: tracking.NewHTTP2Tracker(),
+		echConfigs:   make(map[string]echEntry),
+		clientHints:  newClientHintStore(),
+	}
+	client.dialer.SetTracker(client.tracker)
+	client.dialer.SetDialFunc(client.dialRaw)
+	client.dialer.SetClientHelloFunc(client.clientHelloSpec)
+	client.dialer.SetApplicationSettings(client.applicationSettings())
+	if !opts.DisableSessionResumption {
+		client.dialer.SetSessionCache(cacheSizeFor(opts))
+	}
+
+	client.pins, err = normalizePins(opts.Pins)
+	if err != nil {
+		return nil, err
+	}
+	if len(client.pins) > 0 || opts.VerifyConnection != nil {
+		client.dialer.SetVerifyFunc(client.verifyConnection)
+	}
+
+	client.clientCerts, err = loadClientCertificates(opts.ClientCertificates, profile.SignatureAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	client.resolver, err = client.newResolver(opts)
+	if err != nil {
+		return nil, err
+	}
 	
 	transport := &http.Transport{
-		TLSClientConfig: tlsConfig,
-		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return trackedDialer.DialTLS(network, addr, tlsConfig)
-		},
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		TLSClientConfig:       tlsConfig.Clone(),
+		DialTLSContext:        client.dialTLS,
+		DialContext:           client.dialPlain,
 		MaxIdleConns:          100,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 		ForceAttemptHTTP2:     true,
+		Protocols:             opts.Protocol.transportProtocols(),
 	}
 
-	httpClient := &http.Client{
-		Transport: transport,
-		Timeout:   30 * time.Second,
+	client.transport = transport
+
+	fresh := transport.Clone()
+	fresh.DisableKeepAlives = true
+
+	var roundTripper, freshRoundTripper http.RoundTripper = transport, fresh
+	if opts.HTTP3 {
+		cache := newAltSvcCache()
+		roundTripper = &altSvcTransport{
+			tcp:       transport,
+			h3:        client.newHTTP3Transport(cache, false),
+			cache:     cache,
+			earlyData: opts.EarlyData,
+		}
+		freshRoundTripper = &altSvcTransport{
+			tcp:       fresh,
+			h3:        client.newHTTP3Transport(cache, true),
+			cache:     cache,
+			earlyData: opts.EarlyData,
+		}
 	}
 
-	http2Tracker := tracking.NewHTTP2Tracker()
-	
-	defaultFrames := tracking.CreateHTTP2FrameForProfile(profile.Name)
-	for _, frame := range defaultFrames {
-		http2Tracker.TrackFrame(frame)
+	client.middleware = client.newMiddlewareTransport(roundTripper, freshRoundTripper)
+
+	client.httpClient = &http.Client{
+		Transport: client.middleware,
+		Timeout:   30 * time.Second,
 	}
 
-	client := &Client{
-		httpClient:   httpClient,
-		profile:      profile,
-		headers:      NewOrderedHeaders(),
-		tracker:      tracking.GlobalTracker,
-		http2Tracker: http2Tracker,
+	defaultFrames := tracking.CreateHTTP2FramesForProfile(profile)
+	for _, frame := range defaultFrames {
+		client.http2Tracker.TrackFrame(frame)
 	}
 	
-	client.updateFingerprint()
+	client.updateFingerprint(nil, nil)
 	
 	return client, nil
 }
 
+func cacheSizeFor(opts ClientOptions) int {
+	if opts.SessionCacheSize > 0 {
+		return opts.SessionCacheSize
+	}
+	return defaultSessionCacheSize
+}
+
+func (c *Client) dialPlain(ctx context.Context, network, addr string) (net.Conn, error) {
+	ctx, err := c.withProxy(ctx, "http", addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, slot := withDialSlot(ctx)
+	if c.options.Protocol == ProtocolH2C {
+		conn, err := c.dialer.DialCleartext(ctx, network, addr, "h2c")
+		if err != nil {
+			return nil, err
+		}
+		c.connections.bind(conn, slot.conn)
+		return conn, nil
+	}
+
+	conn, err := c.dialer.DialCleartext(ctx, network, addr, "http/1.1")
+	if err != nil {
+		return nil, err
+	}
+	ordered := newHeaderOrderConn(conn, c.profile.HeaderOrder)
+	c.connections.bind(ordered, slot.conn)
+	return ordered, nil
+}
+
+func (c *Client) dialTLS(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	config := c.withClientCertificate(c.tlsConfig, host)
+	if echConfig := c.echConfigFor(ctx, host); echConfig != nil {
+		config = config.Clone()
+		config.MinVersion = tls.VersionTLS13
+		config.EncryptedClientHelloConfigList = echConfig
+	}
+
+	ctx, err = c.withProxy(ctx, "https", addr)
+	if err != nil {
+		return nil, err
+	}
+	recorder := timingRecorderFrom(ctx)
+	ctx, slot := withDialSlot(ctx)
+	conn, err := c.dialer.DialTLSContext(ctx, network, addr, config)
+	if err == nil && recorder != nil {
+		recorder.handshakeDone()
+	}
+
+	var echErr *tls.ECHRejectionError
+	if errors.As(err, &echErr) && len(echErr.RetryConfigList) > 0 {
+		c.storeECHConfig(host, echErr.RetryConfigList, echRetryTTL)
+		config.EncryptedClientHelloConfigList = echErr.RetryConfigList
+		conn, err = c.dialer.DialTLSContext(ctx, network, addr, config)
+		if err == nil && recorder != nil {
+			recorder.handshakeDone()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if state, ok := conn.(tlsConn); ok {
+		switch {
+		case state.ConnectionState().NegotiatedProtocol != "h2":
+			conn = newHeaderOrderConn(conn, c.profile.HeaderOrder)
+		default:
+			conn = newHTTP2HeaderConn(state, c.profile.PseudoHeaderOrder, c.profile.HeaderOrder, !c.profile.CookieCrumbling)
+		}
+	}
+	c.connections.bind(conn, slot.conn)
+	return conn, nil
+}
+
 func (c *Client) Get(targetURL string, headers ...map[string]string) (*Response, error) {
 	var opts *RequestOptions
 	if len(headers) > 0 && headers[0] != nil {
@@ -219,16 +435,29 @@ func (c *Client) Post(targetURL string, body interface{}, headers ...map[string]
 }
 
 func (c *Client) PostJSON(targetURL string, body interface{}, headers ...map[string]string) (*Response, error) {
-	var jsonBody interface{}
-	switch v := body.(type) {
-	case string, []byte:
-		jsonBody = v
-	default:
-		jsonBytes, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal JSON: %w", err)
-		}
-		jsonBody = jsonBytes
+	return c.requestJSON("POST", targetURL, body, headers...)
+}
+
+func (c *Client) PutJSON(targetURL string, body interface{}, headers ...map[string]string) (*Response, error) {
+	return c.requestJSON("PUT", targetURL, body, headers...)
+}
+
+func (c *Client) PatchJSON(targetURL string, body interface{}, headers ...map[string]string) (*Response, error) {
+	return c.requestJSON("PATCH", targetURL, body, headers...)
+}
+
+func (c *Client) PostForm(targetURL string, form url.Values, headers ...map[string]string) (*Response, error) {
+	return c.Post(targetURL, form, headers...)
+}
+
+func (c *Client) PostMultipart(targetURL string, form *Multipart, headers ...map[string]string) (*Response, error) {
+	return c.Post(targetURL, form, headers...)
+}
+
+func (c *Client) requestJSON(method, targetURL string, body interface{}, headers ...map[string]string) (*Response, error) {
+	jsonBody, err := marshalJSON(body)
+	if err != nil {
+		return nil, err
 	}
 	
 	mergedHeaders := make(map[string]string)
@@ -242,7 +471,7 @@ func (c *Client) PostJSON(targetURL string, body interface{}, headers ...map[str
 	}
 	
 	opts := &RequestOptions{Headers: mergedHeaders}
-	return c.Request("POST", targetURL, jsonBody, opts)
+	return c.Request(method, targetURL, jsonBody, opts)
 }
 
 func (c *Client) Put(targetURL string, body interface{}, headers ...map[string]string) (*Response, error) {
@@ -299,50 +528,51 @@ func (c *Client) Request(method, targetURL string, body interface{}, options *Re
 		parsedURL.RawQuery = q.Encode()
 	}
 
-	var bodyReader io.Reader
-	
-	if body != nil {
-		switch v := body.(type) {
-		case string:
-			bodyReader = strings.NewReader(v)
-		case []byte:
-			bodyReader = strings.NewReader(string(v))
-		case io.Reader:
-			bodyReader = v
-		case url.Values:
-			bodyReader = strings.NewReader(v.Encode())
-		default:
-			return nil, fmt.Errorf("unsupported body type: %T", body)
-		}
+	bodyReader, contentType, err := c.encodeBody(body)
+	if err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequest(method, parsedURL.String(), bodyReader)
+	start := time.Now()
+	ctx, holder := withFingerprintHolder(context.Background())
+	ctx, recorder := withTimingRecorder(ctx, start)
+	if options != nil && options.ForceNewConnection {
+		ctx = context.WithValue(ctx, forceNewConnectionKey{}, true)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, parsedURL.String(), bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	c.applyAllHeaders(req, method, parsedURL, options)
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 
 	if options != nil && options.Cookies != nil {
 		for _, cookie := range options.Cookies {
 			req.AddCookie(cookie)
 		}
 	}
-
-	if c.http2Tracker != nil {
-		headersFrame := fingerprint.Frame{
-			Type:     "HEADERS",
-			StreamID: 1,
-			Length:   uint32(tracking.EstimateHeadersSize(req)),
-			Flags:    []string{"EndStream", "EndHeaders"},
-			Headers:  tracking.ExtractHeadersList(req),
-		}
-		c.http2Tracker.TrackFrame(headersFrame)
-	}
+	c.crumbleCookies(req.Header)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", c.classifyError(err, parsedURL, holder.get()))
+	}
+
+	if intent := requestIntent(method, options); intent == IntentNavigate || intent == IntentFormSubmit {
+		c.clientHints.update(urlOrigin(resp.Request.URL), resp.Header)
+		if c.missingCriticalHints(resp) {
+			if retry, ok := c.retryWithClientHints(req.Context(), resp); ok {
+				resp.Body.Close()
+				resp, err = c.httpClient.Do(retry)
+				if err != nil {
+					return nil, fmt.Errorf("request failed: %w", c.classifyError(err, parsedURL, holder.get()))
+				}
+				c.clientHints.update(urlOrigin(resp.Request.URL), resp.Header)
+			}
+		}
 	}
 
 	responseBody, err := io.ReadAll(resp.Body)
@@ -352,94 +582,126 @@ func (c *Client) Request(method, targetURL string, body interface{}, options *Re
 	}
 	resp.Body.Close()
 
-	c.updateFingerprint()
+	fp := holder.get()
+	if fp == nil {
+		fp = tracking.GenerateFingerprintData(c.profile, nil)
+	}
 
 	response := &Response{
 		Response:    resp,
-		Text:        string(responseBody),
-		Fingerprint: c.lastFingerprint,
+		Text:        decodeText(resp.Header.Get("Content-Type"), responseBody),
+		URL:         parsedURL.String(),
+		Elapsed:     time.Since(start),
+		Timings:     recorder.result(),
+		Fingerprint: fp,
+		body:        responseBody,
 	}
 
-	response.Body = io.NopCloser(strings.NewReader(response.Text))
+	if resp.Request != nil {
+		response.URL = resp.Request.URL.String()
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
 
 	return response, nil
 }
 
-func (c *Client) updateFingerprint() {
+func (c *Client) updateFingerprint(tracked *tracking.ConnectionDetails, req *http.Request) *fingerprint.Data {
 	var details *tracking.ConnectionDetails
-	connections := c.tracker.GetAllConnections()
-	
-	var latestTime time.Time
-	for _, conn := range connections {
-		if conn.ConnectedAt.After(latestTime) {
-			latestTime = conn.ConnectedAt
-			details = conn
-		}
+	if tracked != nil {
+		snapshot := *tracked
+		details = &snapshot
 	}
 	
-	if details != nil {
+	if details != nil && (!details.Cleartext || details.Protocol == "h2c") {
 		details.HTTP2Settings = c.http2Tracker.GetSettings()
 		details.HTTP2Frames = c.http2Tracker.GetFrames()
 		details.HTTP2WindowUpdate = c.http2Tracker.GetWindowSize()
 		details.HTTP2Priority = c.http2Tracker.GetPriority()
+		if req != nil {
+			details.HTTP2Frames = append(details.HTTP2Frames, headersFrame(req))
+		}
 	}
 	
-	c.lastFingerprint = tracking.GenerateFingerprintData(c.profile, details)
+	fp := tracking.GenerateFingerprintData(c.profile, details)
+	
+	c.fingerprintMu.Lock()
+	c.lastFingerprint = fp
+	c.fingerprintMu.Unlock()
+	
+	return fp
+}
+
+func (c *Client) currentFingerprint() *fingerprint.Data {
+	c.fingerprintMu.RLock()
+	defer c.fingerprintMu.RUnlock()
+	return c.lastFingerprint
+}
+
+func headersFrame(req *http.Request) fingerprint.Frame {
+	return fingerprint.Frame{
+		Type:     "HEADERS",
+		StreamID: 1,
+		Length:   uint32(tracking.EstimateHeadersSize(req)),
+		Flags:    []string{"EndStream", "EndHeaders"},
+		Headers:  tracking.ExtractHeadersList(req),
+	}
 }
 
 func (c *Client) applyAllHeaders(req *http.Request, method string, parsedURL *url.URL, opts *RequestOptions) {
 	req.Header = make(http.Header)
 	
-	hasUserHeaders := c.hasUserHeaders(opts)
+	mode := HeaderMerge
+	if opts != nil {
+		mode = opts.HeaderMode
+	}
 	
-	if hasUserHeaders {
-		for _, h := range c.headers.headers {
-			req.Header.Set(h.name, h.value)
-		}
-		
-		c.applyRequestHeaders(req, opts)
-		
-		if req.Header.Get("User-Agent") == "" {
-			req.Header.Set("User-Agent", c.profile.UserAgent)
-		}
-		if req.Header.Get("Accept") == "" {
-			req.Header.Set("Accept", c.profile.Accept)
-		}
-		if req.Header.Get("Accept-Language") == "" {
-			req.Header.Set("Accept-Language", c.profile.AcceptLanguage)
-		}
-		if req.Header.Get("Accept-Encoding") == "" {
-			req.Header.Set("Accept-Encoding", c.profile.AcceptEncoding)
-		}
-	} else {
-		profileHeaders := c.getProfileHeaders(method, parsedURL)
+	if mode != HeaderReplace {
+		profileHeaders := c.getProfileHeaders(method, parsedURL, opts)
 		for _, headerName := range c.profile.HeaderOrder {
 			if strings.HasPrefix(headerName, ":") {
 				continue
 			}
 			
-			if value, exists := profileHeaders[headerName]; exists {
-				req.Header.Set(headerName, value)
+			for name, value := range profileHeaders {
+				if strings.EqualFold(name, headerName) {
+					req.Header.Set(name, value)
+				}
 			}
 		}
 		
-		for _, h := range c.headers.headers {
-			req.Header.Set(h.name, h.value)
+		if value, exists := profileHeaders["Connection"]; exists {
+			req.Header.Set("Connection", value)
+		}
+	}
+	
+	if mode != HeaderProfileOnly {
+		writeHeaders(req.Header, c.headers.snapshot())
+		c.applyRequestHeaders(req, opts)
+	}
+	
+	if mode == HeaderReplace && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.profile.UserAgent)
+	}
+	
+	if opts != nil {
+		for _, name := range opts.RemoveHeaders {
+			req.Header.Del(name)
+			if strings.EqualFold(name, "User-Agent") {
+				req.Header["User-Agent"] = nil
+			}
 		}
 	}
 	
 	req.Host = parsedURL.Host
 }
 
-func (c *Client) hasUserHeaders(opts *RequestOptions) bool {
-	if opts == nil {
-		return false
+func (c *Client) sendsHeader(name string) bool {
+	for _, headerName := range c.profile.HeaderOrder {
+		if strings.EqualFold(headerName, name) {
+			return true
+		}
 	}
-	
-	return (opts.Headers != nil && len(opts.Headers) > 0) ||
-		   (opts.HeadersSlice != nil && len(opts.HeadersSlice) > 0) ||
-		   (opts.HeadersStringList != nil && len(opts.HeadersStringList) > 0) ||
-		   (opts.HeadersJSON != "" && opts.HeadersJSON != "{}")
+	return false
 }
 
 func (c *Client) applyRequestHeaders(req *http.Request, opts *RequestOptions) {
@@ -454,20 +716,22 @@ func (c *Client) applyRequestHeaders(req *http.Request, opts *RequestOptions) {
 	}
 	
 	if opts.HeadersSlice != nil {
+		set := headerSetter(req.Header)
 		for _, pair := range opts.HeadersSlice {
 			if len(pair) == 2 {
-				req.Header.Set(pair[0], pair[1])
+				set(pair[0], pair[1])
 			}
 		}
 	}
 	
 	if opts.HeadersStringList != nil {
+		set := headerSetter(req.Header)
 		for _, headerStr := range opts.HeadersStringList {
 			parts := strings.SplitN(headerStr, ":", 2)
 			if len(parts) == 2 {
 				name := strings.TrimSpace(parts[0])
 				value := strings.TrimSpace(parts[1])
-				req.Header.Set(name, value)
+				set(name, value)
 			}
 		}
 	}
@@ -482,55 +746,78 @@ func (c *Client) applyRequestHeaders(req *http.Request, opts *RequestOptions) {
 	}
 }
 
-func (c *Client) getProfileHeaders(method string, parsedURL *url.URL) map[string]string {
+func headerSetter(dst http.Header) func(name, value string) {
+	seen := make(map[string]bool)
+	return func(name, value string) {
+		key := http.CanonicalHeaderKey(name)
+		if seen[key] {
+			dst.Add(key, value)
+			return
+		}
+		dst.Set(key, value)
+		seen[key] = true
+	}
+}
+
+func writeHeaders(dst http.Header, headers []header) {
+	set := headerSetter(dst)
+	for _, h := range headers {
+		set(h.name, h.value)
+	}
+}
+
+func (c *Client) crumbleCookies(h http.Header) {
+	values := h.Values("Cookie")
+	if len(values) == 0 {
+		return
+	}
+	if !c.profile.CookieCrumbling {
+		h.Set("Cookie", strings.Join(values, "; "))
+		return
+	}
+	
+	var crumbs []string
+	for _, value := range values {
+		for _, crumb := range strings.Split(value, ";") {
+			if crumb = strings.TrimSpace(crumb); crumb != "" {
+				crumbs = append(crumbs, crumb)
+			}
+		}
+	}
+	h["Cookie"] = crumbs
+}
+
+func (c *Client) getProfileHeaders(method string, parsedURL *url.URL, opts *RequestOptions) map[string]string {
 	headers := map[string]string{
 		"User-Agent":      c.profile.UserAgent,
-		"Accept":          c.profile.Accept,
 		"Accept-Language": c.profile.AcceptLanguage,
 		"Accept-Encoding": c.profile.AcceptEncoding,
 	}
 
-	switch c.profile.Name {
-	case "Chrome120", "Edge120":
-		headers["sec-ch-ua"] = `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`
-		headers["sec-ch-ua-mobile"] = "?0"
-		headers["sec-ch-ua-platform"] = `"Windows"`
-		headers["DNT"] = "1"
-		headers["Connection"] = "keep-alive"
-		headers["Upgrade-Insecure-Requests"] = "1"
-		
-		switch method {
-		case "GET":
-			headers["Sec-Fetch-Site"] = "none"
-			headers["Sec-Fetch-Mode"] = "navigate"
-			headers["Sec-Fetch-User"] = "?1"
-			headers["Sec-Fetch-Dest"] = "document"
-		case "POST", "PUT", "PATCH":
-			headers["Sec-Fetch-Site"] = "same-origin"
-			headers["Sec-Fetch-Mode"] = "cors"
-			headers["Sec-Fetch-Dest"] = "empty"
-		default:
-			headers["Sec-Fetch-Site"] = "same-origin"
-			headers["Sec-Fetch-Mode"] = "cors"
-			headers["Sec-Fetch-Dest"] = "empty"
-		}
-		
-	case "Firefox121":
-		headers["DNT"] = "1"
-		headers["Connection"] = "keep-alive"
-		headers["Upgrade-Insecure-Requests"] = "1"
-		
-	case "Safari17":
-		headers["Connection"] = "keep-alive"
-		headers["Upgrade-Insecure-Requests"] = "1"
+	for key, value := range c.intentHeaders(method, parsedURL, opts) {
+		headers[key] = value
 	}
 
 	if c.profile.SecHeaders != nil {
+		trustworthy := potentiallyTrustworthy(parsedURL)
 		for key, value := range c.profile.SecHeaders {
+			if !trustworthy && strings.HasPrefix(strings.ToLower(key), "sec-ch-") {
+				continue
+			}
 			headers[key] = value
 		}
 	}
 
+	for key, value := range c.clientHintHeaders(parsedURL) {
+		headers[key] = value
+	}
+
+	if c.expectedProtocol(parsedURL) == "h2" {
+		delete(headers, "Connection")
+	} else {
+		headers["Connection"] = "keep-alive"
+	}
+
 	return headers
 }
 
@@ -543,53 +830,53 @@ func (c *Client) GetJA3Hash() string {
 }
 
 func (c *Client) GetJA4() string {
-	if c.lastFingerprint != nil {
-		return c.lastFingerprint.JA4
+	if fp := c.currentFingerprint(); fp != nil && fp.JA4 != "" {
+		return fp.JA4
 	}
 	
 	tlsVersion, cipherSuites, extensions, supportedGroups, _, err := fingerprint.ParseJA3(c.profile.JA3)
 	if err != nil {
 		return ""
 	}
-	return fingerprint.GenerateJA4(tlsVersion, cipherSuites, extensions, supportedGroups, c.profile.ALPNProtocols)
+	return fingerprint.GenerateJA4(tlsVersion, cipherSuites, extensions, supportedGroups, c.tlsConfig.NextProtos)
 }
 
 func (c *Client) GetJA4R() string {
-	if c.lastFingerprint != nil {
-		return c.lastFingerprint.JA4_R
+	if fp := c.currentFingerprint(); fp != nil && fp.JA4_R != "" {
+		return fp.JA4_R
 	}
 	
 	tlsVersion, cipherSuites, extensions, supportedGroups, _, err := fingerprint.ParseJA3(c.profile.JA3)
 	if err != nil {
 		return ""
 	}
-	return fingerprint.GenerateJA4R(tlsVersion, cipherSuites, extensions, supportedGroups, c.profile.ALPNProtocols)
+	return fingerprint.GenerateJA4R(tlsVersion, cipherSuites, extensions, supportedGroups, c.tlsConfig.NextProtos)
 }
 
 func (c *Client) GetPeetPrint() string {
-	if c.lastFingerprint != nil {
-		return c.lastFingerprint.PeetPrint
+	if fp := c.currentFingerprint(); fp != nil {
+		return fp.PeetPrint
 	}
 	return ""
 }
 
 func (c *Client) GetAkamaiFingerprint() string {
-	if c.lastFingerprint != nil {
-		return c.lastFingerprint.AkamaiFP
+	if fp := c.currentFingerprint(); fp != nil {
+		return fp.AkamaiFP
 	}
 	return ""
 }
 
 func (c *Client) GetClientRandom() string {
-	if c.lastFingerprint != nil {
-		return c.lastFingerprint.ClientRandom
+	if fp := c.currentFingerprint(); fp != nil {
+		return fp.ClientRandom
 	}
 	return ""
 }
 
 func (c *Client) GetSessionID() string {
-	if c.lastFingerprint != nil {
-		return c.lastFingerprint.SessionID
+	if fp := c.currentFingerprint(); fp != nil {
+		return fp.SessionID
 	}
 	return ""
 }
@@ -614,10 +901,22 @@ func (c *Client) SetHeadersFromJSON(jsonStr string) error {
 	return c.headers.SetFromJSON(jsonStr)
 }
 
+func (c *Client) AddHeader(name, value string) {
+	c.headers.Add(name, value)
+}
+
 func (c *Client) GetHeader(name string) string {
 	return c.headers.Get(name)
 }
 
+func (c *Client) GetHeaderValues(name string) []string {
+	return c.headers.Values(name)
+}
+
+func (c *Client) Headers() *OrderedHeaders {
+	return c.headers
+}
+
 func (c *Client) GetHeaders() map[string]string {
 	return c.headers.GetAll()
 }
@@ -640,22 +939,108 @@ func NewOrderedHeaders() *OrderedHeaders {
 	}
 }
 
+func (oh *OrderedHeaders) snapshot() []header {
+	oh.mu.RLock()
+	defer oh.mu.RUnlock()
+	return oh.headers
+}
+
 func (oh *OrderedHeaders) Set(name, value string) {
 	name = strings.ToLower(name)
 	
+	oh.mu.Lock()
+	defer oh.mu.Unlock()
+	
+	headers := make([]header, 0, len(oh.headers)+1)
+	replaced := false
+	for _, h := range oh.headers {
+		if h.name != name {
+			headers = append(headers, h)
+			continue
+		}
+		if !replaced {
+			headers = append(headers, header{name: name, value: value})
+			replaced = true
+		}
+	}
+	
+	if !replaced {
+		headers = append(headers, header{name: name, value: value})
+	}
+	oh.headers = headers
+}
+
+func (oh *OrderedHeaders) Add(name, value string) {
+	name = strings.ToLower(name)
+	
+	oh.mu.Lock()
+	defer oh.mu.Unlock()
+	
+	headers := make([]header, len(oh.headers), len(oh.headers)+1)
+	copy(headers, oh.headers)
+	oh.headers = append(headers, header{name: name, value: value})
+}
+
+func (oh *OrderedHeaders) InsertBefore(before, name, value string) {
+	oh.insert(strings.ToLower(before), name, value, false)
+}
+
+func (oh *OrderedHeaders) InsertAfter(after, name, value string) {
+	oh.insert(strings.ToLower(after), name, value, true)
+}
+
+func (oh *OrderedHeaders) insert(mark, name, value string, after bool) {
+	name = strings.ToLower(name)
+	
+	oh.mu.Lock()
+	defer oh.mu.Unlock()
+	
+	index := len(oh.headers)
 	for i, h := range oh.headers {
+		if h.name != mark {
+			continue
+		}
+		if !after {
+			index = i
+			break
+		}
+		index = i + 1
+	}
+	
+	headers := make([]header, 0, len(oh.headers)+1)
+	headers = append(headers, oh.headers[:index]...)
+	headers = append(headers, header{name: name, value: value})
+	oh.headers = append(headers, oh.headers[index:]...)
+}
+
+func (oh *OrderedHeaders) MoveTo(name string, index int) {
+	name = strings.ToLower(name)
+	
+	oh.mu.Lock()
+	defer oh.mu.Unlock()
+	
+	var moved, rest []header
+	for _, h := range oh.headers {
 		if h.name == name {
-			oh.headers[i].value = value
-			return
+			moved = append(moved, h)
+		} else {
+			rest = append(rest, h)
 		}
 	}
+	if len(moved) == 0 {
+		return
+	}
 	
-	oh.headers = append(oh.headers, header{name: name, value: value})
+	index = min(max(index, 0), len(rest))
+	headers := make([]header, 0, len(oh.headers))
+	headers = append(headers, rest[:index]...)
+	headers = append(headers, moved...)
+	oh.headers = append(headers, rest[index:]...)
 }
 
 func (oh *OrderedHeaders) Get(name string) string {
 	name = strings.ToLower(name)
-	for _, h := range oh.headers {
+	for _, h := range oh.snapshot() {
 		if h.name == name {
 			return h.value
 		}
@@ -663,14 +1048,30 @@ func (oh *OrderedHeaders) Get(name string) string {
 	return ""
 }
 
-func (oh *OrderedHeaders) Del(name string) {
+func (oh *OrderedHeaders) Values(name string) []string {
 	name = strings.ToLower(name)
-	for i, h := range oh.headers {
+	var values []string
+	for _, h := range oh.snapshot() {
 		if h.name == name {
-			oh.headers = append(oh.headers[:i], oh.headers[i+1:]...)
-			return
+			values = append(values, h.value)
+		}
+	}
+	return values
+}
+
+func (oh *OrderedHeaders) Del(name string) {
+	name = strings.ToLower(name)
+	
+	oh.mu.Lock()
+	defer oh.mu.Unlock()
+	
+	headers := make([]header, 0, len(oh.headers))
+	for _, h := range oh.headers {
+		if h.name != name {
+			headers = append(headers, h)
 		}
 	}
+	oh.headers = headers
 }
 
 func (oh *OrderedHeaders) SetMultiple(headers map[string]string) {
@@ -680,16 +1081,18 @@ func (oh *OrderedHeaders) SetMultiple(headers map[string]string) {
 }
 
 func (oh *OrderedHeaders) SetFromSlice(headers [][]string) error {
+	seen := make(map[string]bool)
 	for _, pair := range headers {
 		if len(pair) != 2 {
 			return fmt.Errorf("invalid header pair: expected [name, value], got %v", pair)
 		}
-		oh.Set(pair[0], pair[1])
+		oh.setOrAdd(seen, pair[0], pair[1])
 	}
 	return nil
 }
 
 func (oh *OrderedHeaders) SetFromStringSlice(headers []string) error {
+	seen := make(map[string]bool)
 	for _, headerStr := range headers {
 		parts := strings.SplitN(headerStr, ":", 2)
 		if len(parts) != 2 {
@@ -697,11 +1100,21 @@ func (oh *OrderedHeaders) SetFromStringSlice(headers []string) error {
 		}
 		name := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
-		oh.Set(name, value)
+		oh.setOrAdd(seen, name, value)
 	}
 	return nil
 }
 
+func (oh *OrderedHeaders) setOrAdd(seen map[string]bool, name, value string) {
+	key := strings.ToLower(name)
+	if seen[key] {
+		oh.Add(name, value)
+		return
+	}
+	oh.Set(name, value)
+	seen[key] = true
+}
+
 func (oh *OrderedHeaders) SetFromJSON(jsonStr string) error {
 	var headers map[string]string
 	if err := json.Unmarshal([]byte(jsonStr), &headers); err != nil {
@@ -712,20 +1125,25 @@ func (oh *OrderedHeaders) SetFromJSON(jsonStr string) error {
 }
 
 func (oh *OrderedHeaders) Clear() {
+	oh.mu.Lock()
+	defer oh.mu.Unlock()
 	oh.headers = make([]header, 0)
 }
 
 func (oh *OrderedHeaders) GetAll() map[string]string {
 	result := make(map[string]string)
-	for _, h := range oh.headers {
-		result[h.name] = h.value
+	for _, h := range oh.snapshot() {
+		if _, exists := result[h.name]; !exists {
+			result[h.name] = h.value
+		}
 	}
 	return result
 }
 
 func (oh *OrderedHeaders) GetAllOrdered() [][]string {
-	result := make([][]string, len(oh.headers))
-	for i, h := range oh.headers {
+	headers := oh.snapshot()
+	result := make([][]string, len(headers))
+	for i, h := range headers {
 		result[i] = []string{h.name, h.value}
 	}
 	return result
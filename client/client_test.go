@@ -0,0 +1,289 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rip-zoyo/orbit-tls/profiles"
+)
+
+func TestConcurrentRequestsShareConfig(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if resp.Text != "HTTP/2.0" {
+				t.Errorf("served over %s, want HTTP/2.0", resp.Text)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if c.GetJA4() == "" || c.GetJA4R() == "" {
+				t.Error("empty JA4 while requests are in flight")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSharedClientConcurrentUse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.UserAgent() != Chrome138.profile.UserAgent {
+			http.Error(w, "unexpected user agent "+r.UserAgent(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Request")))
+	}))
+	defer server.Close()
+
+	baseProfile, err := json.Marshal(Chrome138.profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseHeaders := Chrome138.GetHeadersOrdered()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("X-Worker-%d", i)
+			id := fmt.Sprint(i)
+			for j := 0; j < 5; j++ {
+				Chrome138.SetHeader(name, id)
+				Chrome138.AddHeader(name, id)
+				if got := Chrome138.GetHeaderValues(name); len(got) != 2 {
+					t.Errorf("%s values = %q, want two", name, got)
+				}
+
+				resp, err := Chrome138.Get(server.URL, map[string]string{"X-Request": id})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if resp.StatusCode != http.StatusOK || resp.Text != id {
+					t.Errorf("request %s answered %d %q", id, resp.StatusCode, resp.Text)
+				}
+
+				req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+				req.Header.Set("X-Request", id)
+				httpResp, err := Chrome138.HTTPClient().Do(req)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				httpResp.Body.Close()
+				if httpResp.StatusCode != http.StatusOK {
+					t.Errorf("HTTPClient request %s answered %d", id, httpResp.StatusCode)
+				}
+				if Chrome138.GetJA4() == "" {
+					t.Error("no fingerprint while requests are in flight")
+				}
+
+				Chrome138.DelHeader(name)
+			}
+		}()
+	}
+	wg.Wait()
+
+	profile, err := json.Marshal(Chrome138.profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(profile) != string(baseProfile) {
+		t.Error("concurrent use changed the shared client's profile")
+	}
+	fresh, err := profiles.Get("Chrome138")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fresh, Chrome138.profile) {
+		t.Error("shared client's profile no longer matches the registered Chrome138 profile")
+	}
+	if headers := Chrome138.GetHeadersOrdered(); !reflect.DeepEqual(headers, baseHeaders) {
+		t.Errorf("shared headers = %q, want %q", headers, baseHeaders)
+	}
+}
+
+func TestOrderedHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(oh *OrderedHeaders)
+		want  [][]string
+	}{
+		{"insert before missing anchor appends", func(oh *OrderedHeaders) {
+			oh.InsertBefore("x-missing", "X-New", "1")
+		}, [][]string{{"accept", "*/*"}, {"cookie", "a=1"}, {"user-agent", "ua"}, {"cookie", "b=2"}, {"x-new", "1"}}},
+		{"insert after missing anchor appends", func(oh *OrderedHeaders) {
+			oh.InsertAfter("x-missing", "X-New", "1")
+		}, [][]string{{"accept", "*/*"}, {"cookie", "a=1"}, {"user-agent", "ua"}, {"cookie", "b=2"}, {"x-new", "1"}}},
+		{"insert before first duplicate", func(oh *OrderedHeaders) {
+			oh.InsertBefore("Cookie", "X-New", "1")
+		}, [][]string{{"accept", "*/*"}, {"x-new", "1"}, {"cookie", "a=1"}, {"user-agent", "ua"}, {"cookie", "b=2"}}},
+		{"insert after last duplicate", func(oh *OrderedHeaders) {
+			oh.InsertAfter("cookie", "X-New", "1")
+		}, [][]string{{"accept", "*/*"}, {"cookie", "a=1"}, {"user-agent", "ua"}, {"cookie", "b=2"}, {"x-new", "1"}}},
+		{"move to own position", func(oh *OrderedHeaders) {
+			oh.MoveTo("accept", 0)
+		}, [][]string{{"accept", "*/*"}, {"cookie", "a=1"}, {"user-agent", "ua"}, {"cookie", "b=2"}}},
+		{"move keeps duplicates together", func(oh *OrderedHeaders) {
+			oh.MoveTo("Cookie", 0)
+		}, [][]string{{"cookie", "a=1"}, {"cookie", "b=2"}, {"accept", "*/*"}, {"user-agent", "ua"}}},
+		{"move past the end clamps", func(oh *OrderedHeaders) {
+			oh.MoveTo("accept", 99)
+		}, [][]string{{"cookie", "a=1"}, {"user-agent", "ua"}, {"cookie", "b=2"}, {"accept", "*/*"}}},
+		{"move missing name is a no-op", func(oh *OrderedHeaders) {
+			oh.MoveTo("x-missing", 0)
+		}, [][]string{{"accept", "*/*"}, {"cookie", "a=1"}, {"user-agent", "ua"}, {"cookie", "b=2"}}},
+		{"set collapses duplicates in place", func(oh *OrderedHeaders) {
+			oh.Set("Cookie", "c=3")
+		}, [][]string{{"accept", "*/*"}, {"cookie", "c=3"}, {"user-agent", "ua"}}},
+		{"add keeps duplicates", func(oh *OrderedHeaders) {
+			oh.Add("Accept", "text/html")
+		}, [][]string{{"accept", "*/*"}, {"cookie", "a=1"}, {"user-agent", "ua"}, {"cookie", "b=2"}, {"accept", "text/html"}}},
+		{"del removes every duplicate", func(oh *OrderedHeaders) {
+			oh.Del("COOKIE")
+		}, [][]string{{"accept", "*/*"}, {"user-agent", "ua"}}},
+		{"string slice keeps repeated names", func(oh *OrderedHeaders) {
+			oh.Clear()
+			oh.SetFromStringSlice([]string{"Cookie: x=1", "Accept: */*", "Cookie: y=2"})
+		}, [][]string{{"cookie", "x=1"}, {"accept", "*/*"}, {"cookie", "y=2"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oh := NewOrderedHeaders()
+			oh.Set("Accept", "*/*")
+			oh.Add("Cookie", "a=1")
+			oh.Set("User-Agent", "ua")
+			oh.Add("Cookie", "b=2")
+
+			before := &OrderedHeaders{headers: oh.snapshot()}
+			want := before.GetAllOrdered()
+
+			tt.apply(oh)
+			if got := oh.GetAllOrdered(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("headers = %q, want %q", got, tt.want)
+			}
+			if got := before.GetAllOrdered(); !reflect.DeepEqual(got, want) {
+				t.Errorf("earlier snapshot changed to %q after a write", got)
+			}
+		})
+	}
+}
+
+func TestOrderedHeadersValues(t *testing.T) {
+	oh := NewOrderedHeaders()
+	oh.Add("Cookie", "a=1")
+	oh.Add("Accept", "*/*")
+	oh.Add("cookie", "b=2")
+
+	if got := oh.Values("COOKIE"); !reflect.DeepEqual(got, []string{"a=1", "b=2"}) {
+		t.Errorf("Values = %q, want both cookie crumbs", got)
+	}
+	if got := oh.Get("Cookie"); got != "a=1" {
+		t.Errorf("Get = %q, want the first value", got)
+	}
+	if got := oh.GetAll()["cookie"]; got != "a=1" {
+		t.Errorf("GetAll cookie = %q, want the first value", got)
+	}
+	if got := oh.Values("x-missing"); got != nil {
+		t.Errorf("Values for a missing header = %q, want nil", got)
+	}
+}
+
+func TestHeaderModes(t *testing.T) {
+	profileSet := []string{
+		"accept", "accept-encoding", "accept-language", "priority",
+		"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform",
+		"sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site", "sec-fetch-user",
+		"upgrade-insecure-requests", "user-agent",
+	}
+	tests := []struct {
+		name       string
+		opts       *RequestOptions
+		want       []string
+		wantAccept string
+	}{
+		{"merge adds to the profile set", &RequestOptions{
+			Headers: map[string]string{"Authorization": "Bearer token"},
+		}, slices.Concat(profileSet, []string{"authorization"}), ""},
+		{"merge overrides profile values", &RequestOptions{
+			Headers: map[string]string{"Accept": "application/json"},
+		}, profileSet, "application/json"},
+		{"replace sends only request headers", &RequestOptions{
+			HeaderMode: HeaderReplace,
+			Headers:    map[string]string{"Authorization": "Bearer token"},
+		}, []string{"accept-encoding", "authorization", "user-agent"}, ""},
+		{"profile only ignores request headers", &RequestOptions{
+			HeaderMode: HeaderProfileOnly,
+			Headers:    map[string]string{"Authorization": "Bearer token", "Accept": "application/json"},
+		}, profileSet, ""},
+		{"remove drops profile headers", &RequestOptions{
+			Headers:       map[string]string{"Authorization": "Bearer token"},
+			RemoveHeaders: []string{"Sec-Ch-Ua", "sec-fetch-user", "User-Agent"},
+		}, []string{
+			"accept", "accept-encoding", "accept-language", "authorization", "priority",
+			"sec-ch-ua-mobile", "sec-ch-ua-platform", "sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site",
+			"upgrade-insecure-requests",
+		}, ""},
+	}
+
+	received := make(chan http.Header, 1)
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Request(http.MethodGet, server.URL, nil, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			header := <-received
+
+			var names []string
+			for name := range header {
+				names = append(names, strings.ToLower(name))
+			}
+			sort.Strings(names)
+			want := slices.Clone(tt.want)
+			sort.Strings(want)
+			if !slices.Equal(names, want) {
+				t.Errorf("header set = %q, want %q", names, want)
+			}
+			if tt.wantAccept != "" && header.Get("Accept") != tt.wantAccept {
+				t.Errorf("accept = %q, want %q", header.Get("Accept"), tt.wantAccept)
+			}
+			if slices.Contains(tt.want, "user-agent") && header.Get("User-Agent") != c.profile.UserAgent {
+				t.Errorf("user agent = %q, want the profile's", header.Get("User-Agent"))
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type clientHintStore struct {
+	mu      sync.Mutex
+	origins map[string][]string
+}
+
+func newClientHintStore() *clientHintStore {
+	return &clientHintStore{
+		origins: make(map[string][]string),
+	}
+}
+
+func (s *clientHintStore) accepted(origin string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.origins[origin]
+}
+
+func (s *clientHintStore) update(origin string, header http.Header) {
+	values, present := header[http.CanonicalHeaderKey("Accept-CH")]
+	if !present {
+		return
+	}
+
+	hints := parseHintList(values)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(hints) == 0 {
+		delete(s.origins, origin)
+		return
+	}
+	s.origins[origin] = hints
+}
+
+func parseHintList(values []string) []string {
+	var hints []string
+	for _, value := range values {
+		for _, hint := range strings.Split(value, ",") {
+			hint = strings.ToLower(strings.TrimSpace(hint))
+			if hint != "" {
+				hints = append(hints, hint)
+			}
+		}
+	}
+	return hints
+}
+
+func (c *Client) clientHintHeaders(target *url.URL) map[string]string {
+	if len(c.profile.ClientHints) == 0 || !potentiallyTrustworthy(target) {
+		return nil
+	}
+
+	headers := make(map[string]string)
+	for _, hint := range c.clientHints.accepted(urlOrigin(target)) {
+		if value, ok := c.profile.ClientHints[hint]; ok {
+			headers[hint] = value
+		}
+	}
+	return headers
+}
+
+func (c *Client) missingCriticalHints(resp *http.Response) bool {
+	if len(c.profile.ClientHints) == 0 || !potentiallyTrustworthy(resp.Request.URL) {
+		return false
+	}
+
+	for _, hint := range parseHintList(resp.Header.Values("Critical-CH")) {
+		if _, supported := c.profile.ClientHints[hint]; !supported {
+			continue
+		}
+		if resp.Request.Header.Get(hint) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) retryWithClientHints(ctx context.Context, resp *http.Response) (*http.Request, bool) {
+	original := resp.Request
+	if !idempotentMethod(original.Method) {
+		return nil, false
+	}
+	retry := original.Clone(ctx)
+	if original.Body != nil && original.Body != http.NoBody {
+		if original.GetBody == nil {
+			return nil, false
+		}
+		body, err := original.GetBody()
+		if err != nil {
+			return nil, false
+		}
+		retry.Body = body
+	}
+
+	for name, value := range c.clientHintHeaders(retry.URL) {
+		if retry.Header.Get(name) == "" {
+			retry.Header.Set(name, value)
+		}
+	}
+	return retry, true
+}
+
+func idempotentMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
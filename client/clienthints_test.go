@@ -0,0 +1,102 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type hintRecorder struct {
+	mu     sync.Mutex
+	hints  []string
+	header http.Header
+}
+
+func (r *hintRecorder) handler(header http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.Copy(io.Discard, req.Body)
+		r.mu.Lock()
+		r.hints = append(r.hints, req.Header.Get("Sec-CH-UA-Arch"))
+		r.mu.Unlock()
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+	})
+}
+
+func (r *hintRecorder) seen() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.hints...)
+}
+
+func TestAcceptCHPerOrigin(t *testing.T) {
+	first := &hintRecorder{}
+	firstServer, roots := newTLSServer(t, first.handler(http.Header{"Accept-Ch": {"Sec-CH-UA-Arch"}}))
+	second := &hintRecorder{}
+	secondServer, _ := newTLSServer(t, second.handler(nil))
+	roots.AddCert(secondServer.Certificate())
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{firstServer.URL, firstServer.URL + "/next", secondServer.URL} {
+		if _, err := c.Get(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	arch := c.profile.ClientHints["sec-ch-ua-arch"]
+	if got := first.seen(); len(got) != 2 || got[0] != "" || got[1] != arch {
+		t.Errorf("first origin saw arch hints %q, want none then %q", got, arch)
+	}
+	if got := second.seen(); len(got) != 1 || got[0] != "" {
+		t.Errorf("second origin saw arch hints %q, want none", got)
+	}
+}
+
+func TestCriticalCHRetry(t *testing.T) {
+	critical := http.Header{"Accept-Ch": {"Sec-CH-UA-Arch"}, "Critical-Ch": {"Sec-CH-UA-Arch"}}
+	tests := []struct {
+		name   string
+		header http.Header
+		method string
+		body   func() interface{}
+		opts   *RequestOptions
+		want   int
+	}{
+		{"navigation", critical, "GET", nil, nil, 2},
+		{"hint never accepted", http.Header{"Critical-Ch": {"Sec-CH-UA-Arch"}}, "GET", nil, nil, 2},
+		{"form post", critical, "POST", func() interface{} { return "a=1" }, &RequestOptions{Intent: IntentFormSubmit}, 1},
+		{"unreplayable body", critical, "PUT", func() interface{} { return io.MultiReader(strings.NewReader("a=1")) }, &RequestOptions{Intent: IntentNavigate}, 1},
+		{"replayable body", critical, "PUT", func() interface{} { return strings.NewReader("a=1") }, &RequestOptions{Intent: IntentNavigate}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &hintRecorder{}
+			server, roots := newTLSServer(t, recorder.handler(tt.header))
+			c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body interface{}
+			if tt.body != nil {
+				body = tt.body()
+			}
+			if _, err := c.Request(tt.method, server.URL, body, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			got := recorder.seen()
+			if len(got) != tt.want {
+				t.Errorf("server saw %d requests (arch hints %q), want %d", len(got), got, tt.want)
+			}
+			if len(got) == 2 && tt.header.Get("Accept-Ch") != "" && got[1] != c.profile.ClientHints["sec-ch-ua-arch"] {
+				t.Errorf("retry carried arch hint %q", got[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rip-zoyo/orbit-tls/tracking"
+)
+
+type ConnectionInfo struct {
+	Addr       string                      `json:"addr"`
+	RemoteAddr string                      `json:"remote_addr"`
+	Protocol   string                      `json:"protocol"`
+	Streams    int                         `json:"streams"`
+	Requests   int                         `json:"requests"`
+	CreatedAt  time.Time                   `json:"created_at"`
+	Age        time.Duration               `json:"age"`
+	Details    *tracking.ConnectionDetails `json:"details,omitempty"`
+}
+
+type connectionPool struct {
+	mu    sync.Mutex
+	conns map[*liveConn]struct{}
+	outer map[net.Conn]*liveConn
+}
+
+type liveConn struct {
+	net.Conn
+	pool       *connectionPool
+	addr       string
+	resolution *tracking.Resolution
+	created    time.Time
+	closeOnce  sync.Once
+
+	mu       sync.Mutex
+	details  *tracking.ConnectionDetails
+	outer    net.Conn
+	streams  int
+	requests int
+}
+
+type dialSlotKey struct{}
+
+type dialSlot struct {
+	conn *liveConn
+}
+
+type forceNewConnectionKey struct{}
+
+func newConnectionPool() *connectionPool {
+	return &connectionPool{
+		conns: make(map[*liveConn]struct{}),
+		outer: make(map[net.Conn]*liveConn),
+	}
+}
+
+func (p *connectionPool) add(conn net.Conn, addr string, resolution *tracking.Resolution) *liveConn {
+	live := &liveConn{Conn: conn, pool: p, addr: addr, resolution: resolution, created: time.Now()}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conns[live] = struct{}{}
+	return live
+}
+
+func (p *connectionPool) bind(outer net.Conn, live *liveConn) {
+	if live == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.conns[live]; !ok {
+		return
+	}
+	live.mu.Lock()
+	live.outer = outer
+	live.mu.Unlock()
+	p.outer[outer] = live
+}
+
+func (p *connectionPool) lookup(outer net.Conn) *liveConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.outer[outer]
+}
+
+func (p *connectionPool) remove(live *liveConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.conns, live)
+	live.mu.Lock()
+	if live.outer != nil {
+		delete(p.outer, live.outer)
+	}
+	live.mu.Unlock()
+}
+
+func (p *connectionPool) list() []ConnectionInfo {
+	p.mu.Lock()
+	conns := make([]*liveConn, 0, len(p.conns))
+	for live := range p.conns {
+		conns = append(conns, live)
+	}
+	p.mu.Unlock()
+
+	now := time.Now()
+	infos := make([]ConnectionInfo, 0, len(conns))
+	for _, live := range conns {
+		infos = append(infos, live.info(now))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.Before(infos[j].CreatedAt)
+	})
+	return infos
+}
+
+func (l *liveConn) ReceiveConnectionDetails(details *tracking.ConnectionDetails) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.details = details
+}
+
+func (l *liveConn) Resolution() *tracking.Resolution {
+	return l.resolution
+}
+
+func (l *liveConn) Close() error {
+	l.closeOnce.Do(func() {
+		l.pool.remove(l)
+	})
+	return l.Conn.Close()
+}
+
+func (l *liveConn) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.streams++
+	l.requests++
+}
+
+func (l *liveConn) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.streams--
+}
+
+func (l *liveConn) idle() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.streams == 0
+}
+
+func (l *liveConn) connectionDetails() *tracking.ConnectionDetails {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.details
+}
+
+func (l *liveConn) info(now time.Time) ConnectionInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	protocol := "http/1.1"
+	if l.details != nil && l.details.Protocol != "" {
+		protocol = l.details.Protocol
+	}
+	return ConnectionInfo{
+		Addr:       l.addr,
+		RemoteAddr: l.Conn.RemoteAddr().String(),
+		Protocol:   protocol,
+		Streams:    l.streams,
+		Requests:   l.requests,
+		CreatedAt:  l.created,
+		Age:        now.Sub(l.created),
+		Details:    l.details,
+	}
+}
+
+func (c *Client) dialRaw(ctx context.Context, network, addr string) (net.Conn, error) {
+	var conn net.Conn
+	var resolution *tracking.Resolution
+	var err error
+	if proxy, ok := ctx.Value(proxyKey{}).(*url.URL); ok {
+		conn, resolution, err = c.dialProxy(ctx, network, proxy, addr)
+	} else {
+		conn, resolution, err = c.dialDirect(ctx, network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	live := c.connections.add(conn, addr, resolution)
+	if slot, ok := ctx.Value(dialSlotKey{}).(*dialSlot); ok {
+		slot.conn = live
+	}
+	return live, nil
+}
+
+func (c *Client) dialDirect(ctx context.Context, network, addr string) (net.Conn, *tracking.Resolution, error) {
+	if c.resolver.custom() {
+		return c.dialResolved(ctx, network, addr)
+	}
+	conn, err := c.netDialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, systemResolution(addr, conn), nil
+}
+
+func withDialSlot(ctx context.Context) (context.Context, *dialSlot) {
+	slot := &dialSlot{}
+	return context.WithValue(ctx, dialSlotKey{}, slot), slot
+}
+
+type connectionUse struct {
+	mu   sync.Mutex
+	conn *liveConn
+}
+
+func (c *Client) traceConnection(req *http.Request) (*http.Request, *connectionUse) {
+	use := &connectionUse{}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			live := c.connections.lookup(info.Conn)
+			if live != nil {
+				live.acquire()
+			}
+
+			use.mu.Lock()
+			previous := use.conn
+			use.conn = live
+			use.mu.Unlock()
+			if previous != nil {
+				previous.release()
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), use
+}
+
+func (u *connectionUse) details() *tracking.ConnectionDetails {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.conn == nil {
+		return nil
+	}
+	return u.conn.connectionDetails()
+}
+
+func (u *connectionUse) done() {
+	u.mu.Lock()
+	live := u.conn
+	u.conn = nil
+	u.mu.Unlock()
+	if live != nil {
+		live.release()
+	}
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+func (c *Client) Connections() []ConnectionInfo {
+	return c.connections.list()
+}
+
+func (c *Client) Tracker() *tracking.TLSTracker {
+	return c.tracker
+}
+
+func (c *Client) CloseIdle() {
+	c.middleware.CloseIdleConnections()
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const dnsTypeHTTPS = dnsmessage.Type(65)
+
+const svcParamECH = 5
+
+func buildDNSQuery(host string, qtype dnsmessage.Type) ([]byte, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid DNS name %q: %w", host, err)
+	}
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}
+	return msg.Pack()
+}
+
+func detachedContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(parent, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func dohExchange(ctx context.Context, httpClient *http.Client, resolverURL string, query []byte) ([]byte, error) {
+	ctx, cancel := detachedContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", resolverURL, bytes.NewReader(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DoH request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+func dotExchange(ctx context.Context, server, serverName string, roots *x509.CertPool, query []byte) ([]byte, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		Config:    &tls.Config{ServerName: serverName, RootCAs: roots, MinVersion: tls.VersionTLS12},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, fmt.Errorf("DoT connection failed: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, fmt.Errorf("DoT request failed: %w", err)
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, fmt.Errorf("DoT response failed: %w", err)
+	}
+	answer := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		return nil, fmt.Errorf("DoT response failed: %w", err)
+	}
+	return answer, nil
+}
+
+func parseAddressAnswer(answer []byte) ([]netip.Addr, uint32, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(answer)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid DNS response: %w", err)
+	}
+	if header.RCode != dnsmessage.RCodeSuccess && header.RCode != dnsmessage.RCodeNameError {
+		return nil, 0, fmt.Errorf("DNS server returned %s", header.RCode)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, 0, fmt.Errorf("invalid DNS response: %w", err)
+	}
+
+	var addrs []netip.Addr
+	var ttl uint32
+	for {
+		hdr, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			return addrs, ttl, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid DNS response: %w", err)
+		}
+
+		var addr netip.Addr
+		switch hdr.Type {
+		case dnsmessage.TypeA:
+			res, err := p.AResource()
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid A record: %w", err)
+			}
+			addr = netip.AddrFrom4(res.A)
+		case dnsmessage.TypeAAAA:
+			res, err := p.AAAAResource()
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid AAAA record: %w", err)
+			}
+			addr = netip.AddrFrom16(res.AAAA)
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return nil, 0, fmt.Errorf("invalid DNS response: %w", err)
+			}
+			continue
+		}
+
+		addrs = append(addrs, addr)
+		if ttl == 0 || hdr.TTL < ttl {
+			ttl = hdr.TTL
+		}
+	}
+}
+
+func lookupECHConfig(ctx context.Context, httpClient *http.Client, resolverURL, host string) ([]byte, uint32, error) {
+	query, err := buildDNSQuery(host, dnsTypeHTTPS)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	answer, err := dohExchange(ctx, httpClient, resolverURL, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var p dnsmessage.Parser
+	if _, err := p.Start(answer); err != nil {
+		return nil, 0, fmt.Errorf("invalid DNS response: %w", err)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, 0, fmt.Errorf("invalid DNS response: %w", err)
+	}
+
+	for {
+		hdr, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			return nil, 0, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid DNS response: %w", err)
+		}
+
+		if hdr.Type != dnsTypeHTTPS {
+			if err := p.SkipAnswer(); err != nil {
+				return nil, 0, fmt.Errorf("invalid DNS response: %w", err)
+			}
+			continue
+		}
+
+		res, err := p.UnknownResource()
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid HTTPS record: %w", err)
+		}
+		if ech, ok := parseSVCBECH(res.Data); ok {
+			return ech, hdr.TTL, nil
+		}
+	}
+}
+
+func parseSVCBECH(data []byte) ([]byte, bool) {
+	if len(data) < 2 {
+		return nil, false
+	}
+	data = data[2:]
+
+	for {
+		if len(data) == 0 {
+			return nil, false
+		}
+		labelLen := int(data[0])
+		data = data[1:]
+		if labelLen == 0 {
+			break
+		}
+		if len(data) < labelLen {
+			return nil, false
+		}
+		data = data[labelLen:]
+	}
+
+	for len(data) >= 4 {
+		key := binary.BigEndian.Uint16(data[0:2])
+		valueLen := int(binary.BigEndian.Uint16(data[2:4]))
+		data = data[4:]
+		if len(data) < valueLen {
+			return nil, false
+		}
+		if key == svcParamECH {
+			return data[:valueLen], true
+		}
+		data = data[valueLen:]
+	}
+
+	return nil, false
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+const (
+	echNegativeTTL = 5 * time.Minute
+	echRetryTTL    = time.Hour
+	echMaxTTL      = 24 * time.Hour
+)
+
+type echEntry struct {
+	config  []byte
+	expires time.Time
+}
+
+func (c *Client) echEnabled() bool {
+	return c.options.ECHConfigList != nil || c.options.ECHResolver != ""
+}
+
+func (c *Client) echConfigFor(ctx context.Context, host string) []byte {
+	if c.options.ECHConfigList != nil {
+		return c.options.ECHConfigList
+	}
+	if c.options.ECHResolver == "" {
+		return nil
+	}
+
+	if resolver, err := url.Parse(c.options.ECHResolver); err != nil || resolver.Hostname() == host {
+		return nil
+	}
+
+	c.echMu.Lock()
+	entry, cached := c.echConfigs[host]
+	c.echMu.Unlock()
+	if cached && time.Now().Before(entry.expires) {
+		return entry.config
+	}
+
+	config, ttl, err := lookupECHConfig(ctx, c.httpClient, c.options.ECHResolver, host)
+	if err != nil || len(config) == 0 {
+		c.echMu.Lock()
+		c.echConfigs[host] = echEntry{expires: time.Now().Add(echNegativeTTL)}
+		c.echMu.Unlock()
+		return nil
+	}
+
+	c.storeECHConfig(host, config, min(max(time.Duration(ttl)*time.Second, minDNSCacheTTL), echMaxTTL))
+	return config
+}
+
+func (c *Client) storeECHConfig(host string, config []byte, ttl time.Duration) {
+	c.echMu.Lock()
+	defer c.echMu.Unlock()
+	c.echConfigs[host] = echEntry{config: config, expires: time.Now().Add(ttl)}
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestGREASEECHWithoutConfig(t *testing.T) {
+	var mu sync.Mutex
+	var hellos [][]uint16
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mu.Lock()
+			hellos = append(hellos, hello.Extensions)
+			mu.Unlock()
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	var queries atomic.Int32
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		var query dnsmessage.Message
+		if err := query.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true},
+			Questions: query.Questions,
+		}
+		packed, _ := reply.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	defer doh.Close()
+
+	roots := doh.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	target, _ := url.Parse(server.URL)
+	c, err := NewWithOptions("Chrome131", &ClientOptions{
+		ECHResolver: doh.URL,
+		Hosts:       map[string]string{"example.com": target.Hostname()},
+		RootCAs:     roots,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := "https://example.com:" + target.Port()
+	for i := 0; i < 2; i++ {
+		if _, err := c.Request("GET", endpoint, nil, &RequestOptions{ForceNewConnection: true}); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("HTTPS record queried %d times, want 1 with the empty answer cached", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hellos) != 2 {
+		t.Fatalf("server saw %d handshakes, want 2", len(hellos))
+	}
+	for i, extensions := range hellos {
+		if !slices.Contains(extensions, extensionECH) {
+			t.Errorf("handshake %d did not carry GREASE ECH: %v", i, extensions)
+		}
+	}
+
+	details, ok := c.tracker.GetConnection("example.com:" + target.Port())
+	if !ok {
+		t.Fatal("no tracked connection for example.com")
+	}
+	if !details.ECHOffered || !details.ECHGREASE || details.ECHAccepted {
+		t.Errorf("details = offered %v grease %v accepted %v, want GREASE only",
+			details.ECHOffered, details.ECHGREASE, details.ECHAccepted)
+	}
+}
+
+func echKey(t *testing.T, publicName string) (tls.EncryptedClientHelloKey, []byte) {
+	t.Helper()
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b cryptobyte.Builder
+	b.AddUint16(0xfe0d)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(7)
+		b.AddUint16(0x0020)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(key.PublicKey().Bytes())
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0001)
+			b.AddUint16(0x0001)
+		})
+		b.AddUint8(32)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes([]byte(publicName))
+		})
+		b.AddUint16(0)
+	})
+	config := b.BytesOrPanic()
+
+	var list cryptobyte.Builder
+	list.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(config)
+	})
+	return tls.EncryptedClientHelloKey{Config: config, PrivateKey: key.Bytes(), SendAsRetry: true}, list.BytesOrPanic()
+}
+
+func TestECHAcceptedWithConfigList(t *testing.T) {
+	key, configList := echKey(t, "public.example")
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.ServerName))
+	}))
+	server.TLS = &tls.Config{EncryptedClientHelloKeys: []tls.EncryptedClientHelloKey{key}}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	target, _ := url.Parse(server.URL)
+	c, err := NewWithOptions("Chrome138", &ClientOptions{
+		ECHConfigList: configList,
+		Hosts:         map[string]string{"example.com": target.Hostname()},
+		RootCAs:       roots,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Get("https://example.com:" + target.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "example.com" {
+		t.Errorf("server saw inner name %q, want example.com", resp.Text)
+	}
+
+	details, _ := c.tracker.GetConnection("example.com:" + target.Port())
+	if details == nil || !details.ECHAccepted || details.ECHGREASE {
+		t.Errorf("details = %+v, want accepted ECH", details)
+	}
+}
+
+func TestECHConfigExpiresWithRecordTTL(t *testing.T) {
+	key, configList := echKey(t, "public.example")
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.ServerName))
+	}))
+	server.TLS = &tls.Config{EncryptedClientHelloKeys: []tls.EncryptedClientHelloKey{key}}
+	server.StartTLS()
+	defer server.Close()
+
+	var record cryptobyte.Builder
+	record.AddUint16(1)
+	record.AddUint8(0)
+	record.AddUint16(svcParamECH)
+	record.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(configList)
+	})
+	data := record.BytesOrPanic()
+
+	var queries atomic.Int32
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		var query dnsmessage.Message
+		if err := query.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true},
+			Questions: query.Questions,
+			Answers: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{
+					Name:  query.Questions[0].Name,
+					Type:  dnsTypeHTTPS,
+					Class: dnsmessage.ClassINET,
+					TTL:   120,
+				},
+				Body: &dnsmessage.UnknownResource{Type: dnsTypeHTTPS, Data: data},
+			}},
+		}
+		packed, _ := reply.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	defer doh.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	roots.AddCert(doh.Certificate())
+	target, _ := url.Parse(server.URL)
+	c, err := NewWithOptions("Chrome138", &ClientOptions{
+		ECHResolver: doh.URL,
+		Hosts:       map[string]string{"example.com": target.Hostname()},
+		RootCAs:     roots,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := "https://example.com:" + target.Port()
+	resp, err := c.Request("GET", endpoint, nil, &RequestOptions{ForceNewConnection: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "example.com" {
+		t.Errorf("server saw inner name %q, want example.com", resp.Text)
+	}
+
+	c.echMu.Lock()
+	entry := c.echConfigs["example.com"]
+	c.echMu.Unlock()
+	if remaining := time.Until(entry.expires); remaining <= 110*time.Second || remaining > 120*time.Second {
+		t.Errorf("cached ECH config expires in %v, want the 120s record TTL", remaining)
+	}
+
+	if _, err := c.Request("GET", endpoint, nil, &RequestOptions{ForceNewConnection: true}); err != nil {
+		t.Fatal(err)
+	}
+	if n := queries.Load(); n != 1 {
+		t.Errorf("HTTPS record queried %d times before expiry, want 1", n)
+	}
+
+	c.echMu.Lock()
+	entry.expires = time.Now().Add(-time.Second)
+	c.echConfigs["example.com"] = entry
+	c.echMu.Unlock()
+
+	if _, err := c.Request("GET", endpoint, nil, &RequestOptions{ForceNewConnection: true}); err != nil {
+		t.Fatal(err)
+	}
+	if n := queries.Load(); n != 2 {
+		t.Errorf("HTTPS record queried %d times after expiry, want 2", n)
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+
+	"github.com/rip-zoyo/orbit-tls/fingerprint"
+	"github.com/rip-zoyo/orbit-tls/tracking"
+)
+
+type HandshakeError struct {
+	Host        string
+	Alert       uint8
+	Remote      bool
+	Err         error
+	Fingerprint *fingerprint.Data
+}
+
+func (e *HandshakeError) Error() string {
+	if e.Remote {
+		return fmt.Sprintf("tls handshake with %s failed: alert %d: %v", e.Host, e.Alert, e.Err)
+	}
+	return fmt.Sprintf("tls handshake with %s failed: %v", e.Host, e.Err)
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+type CertificateError struct {
+	Host        string
+	Err         error
+	Fingerprint *fingerprint.Data
+}
+
+func (e *CertificateError) Error() string {
+	return fmt.Sprintf("certificate verification for %s failed: %v", e.Host, e.Err)
+}
+
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+type ProtocolError struct {
+	Protocol    string
+	Code        string
+	GoAway      bool
+	Err         error
+	Fingerprint *fingerprint.Data
+}
+
+func (e *ProtocolError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%s protocol error: %v", e.Protocol, e.Err)
+	}
+	return fmt.Sprintf("%s protocol error %s: %v", e.Protocol, e.Code, e.Err)
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+type ProxyError struct {
+	Proxy string
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("proxy %s: %v", e.Proxy, e.Err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+type TimeoutError struct {
+	Err         error
+	Fingerprint *fingerprint.Data
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeout: %v", e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+type StatusError struct {
+	StatusCode int
+	Status     string
+	URL        string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %s for %s", e.Status, e.URL)
+}
+
+func (c *Client) classifyError(err error, target *url.URL, fp *fingerprint.Data) error {
+	var proxyErr *ProxyError
+	if errors.As(err, &proxyErr) {
+		return proxyErr
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if failed, parseErr := url.Parse(urlErr.URL); parseErr == nil && failed.Host != "" {
+			target = failed
+		}
+	}
+	host := target.Hostname()
+
+	var failure *tracking.HandshakeFailure
+	if errors.As(err, &failure) {
+		fp = tracking.GenerateFingerprintData(c.profile, failure.Details)
+	}
+
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var pinErr *PinError
+	if errors.As(err, &verifyErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) || errors.As(err, &pinErr) {
+		return &CertificateError{Host: host, Err: err, Fingerprint: fp}
+	}
+
+	var alertErr tls.AlertError
+	if errors.As(err, &alertErr) {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "remote error" {
+			return &HandshakeError{Host: host, Alert: uint8(alertErr), Remote: true, Err: err, Fingerprint: fp}
+		}
+		return &HandshakeError{Host: host, Err: err, Fingerprint: fp}
+	}
+	if failure != nil {
+		return &HandshakeError{Host: host, Err: err, Fingerprint: fp}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &TimeoutError{Err: err, Fingerprint: fp}
+	}
+
+	var h3Err *http3.Error
+	if errors.As(err, &h3Err) {
+		return &ProtocolError{Protocol: "h3", Code: h3Err.ErrorCode.String(), Err: err, Fingerprint: fp}
+	}
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) {
+		return &ProtocolError{Protocol: "h3", Code: http3.ErrCode(appErr.ErrorCode).String(), Err: err, Fingerprint: fp}
+	}
+	var transportErr *quic.TransportError
+	if errors.As(err, &transportErr) {
+		return &ProtocolError{Protocol: "quic", Code: transportErr.ErrorCode.String(), Err: err, Fingerprint: fp}
+	}
+
+	var streamErr http2.StreamError
+	if errors.As(err, &streamErr) || asBundledHTTP2(err, &streamErr) {
+		return &ProtocolError{Protocol: "h2", Code: streamErr.Code.String(), Err: err, Fingerprint: fp}
+	}
+	var goAwayErr http2.GoAwayError
+	if errors.As(err, &goAwayErr) || asBundledHTTP2(err, &goAwayErr) {
+		return &ProtocolError{Protocol: "h2", Code: goAwayErr.ErrCode.String(), GoAway: true, Err: err, Fingerprint: fp}
+	}
+	var connErr http2.ConnectionError
+	if errors.As(err, &connErr) || asBundledHTTP2(err, &connErr) {
+		return &ProtocolError{Protocol: "h2", Code: http2.ErrCode(connErr).String(), Err: err, Fingerprint: fp}
+	}
+
+	return err
+}
+
+func asBundledHTTP2(err error, target interface{}) bool {
+	dst := reflect.ValueOf(target).Elem()
+	for ; err != nil; err = errors.Unwrap(err) {
+		src := reflect.ValueOf(err)
+		srcType := src.Type()
+		name := strings.TrimPrefix(srcType.Name(), "http2")
+		if name != dst.Type().Name() || (srcType.PkgPath() != "net/http/internal/http2" && srcType.PkgPath() != "net/http") {
+			continue
+		}
+		if convertHTTP2Value(src, dst) {
+			return true
+		}
+	}
+	return false
+}
+
+func convertHTTP2Value(src, dst reflect.Value) bool {
+	if src.Kind() != reflect.Struct {
+		if !src.Type().ConvertibleTo(dst.Type()) {
+			return false
+		}
+		dst.Set(src.Convert(dst.Type()))
+		return true
+	}
+	if dst.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < dst.NumField(); i++ {
+		field := src.FieldByName(dst.Type().Field(i).Name)
+		if !field.IsValid() || !field.Type().ConvertibleTo(dst.Field(i).Type()) {
+			return false
+		}
+	}
+	for i := 0; i < dst.NumField(); i++ {
+		dst.Field(i).Set(src.FieldByName(dst.Type().Field(i).Name).Convert(dst.Field(i).Type()))
+	}
+	return true
+}
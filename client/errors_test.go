@@ -0,0 +1,407 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"golang.org/x/net/http2"
+
+	"github.com/rip-zoyo/orbit-tls/tracking"
+)
+
+func TestRemoteAlertHandshakeError(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		MinVersion:       tls.VersionTLS13,
+		CurvePreferences: []tls.CurveID{tls.CurveP521},
+	}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Get(server.URL)
+
+	var handshakeErr *HandshakeError
+	if !errors.As(err, &handshakeErr) {
+		t.Fatalf("error = %v (%T), want *HandshakeError", err, err)
+	}
+	if !handshakeErr.Remote || handshakeErr.Alert != 40 {
+		t.Errorf("remote %v alert %d, want remote handshake_failure (40)", handshakeErr.Remote, handshakeErr.Alert)
+	}
+	var alertErr tls.AlertError
+	if !errors.As(err, &alertErr) || alertErr != 40 {
+		t.Errorf("error chain carries alert %v, want tls.AlertError(40)", alertErr)
+	}
+	if handshakeErr.Fingerprint == nil || handshakeErr.Fingerprint.JA3 == "" {
+		t.Error("handshake error did not carry the fingerprint that was sent")
+	}
+}
+
+func TestHandshakeDeadlineIsHandshakeError(t *testing.T) {
+	c, err := NewWithOptions("Chrome138", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target, _ := url.Parse("https://example.com")
+	failure := &url.Error{
+		Op:  "Get",
+		URL: target.String(),
+		Err: &tracking.HandshakeFailure{Addr: "example.com:443", Err: context.DeadlineExceeded},
+	}
+
+	err = c.classifyError(failure, target, nil)
+	var handshakeErr *HandshakeError
+	if !errors.As(err, &handshakeErr) {
+		t.Fatalf("error = %T, want *HandshakeError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("handshake error does not wrap the deadline")
+	}
+}
+
+type connectProxy struct {
+	mu       sync.Mutex
+	targets  []string
+	agents   []string
+	auths    []string
+	rejectAs int
+}
+
+func newConnectProxy(t *testing.T, rejectAs int) (*url.URL, *connectProxy) {
+	t.Helper()
+	proxy := &connectProxy{rejectAs: rejectAs}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxy.mu.Lock()
+		proxy.targets = append(proxy.targets, r.Method+" "+r.Host)
+		proxy.agents = append(proxy.agents, r.UserAgent())
+		proxy.auths = append(proxy.auths, r.Header.Get("Proxy-Authorization"))
+		proxy.mu.Unlock()
+		if r.Method != http.MethodConnect || proxy.rejectAs != 0 {
+			w.WriteHeader(max(proxy.rejectAs, http.StatusMethodNotAllowed))
+			return
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, buffered, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		buffered.WriteString("HTTP/1.1 200 Connection established\r\n\r\n")
+		buffered.Flush()
+		go func() {
+			io.Copy(upstream, buffered)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(server.Close)
+
+	proxyURL, _ := url.Parse(server.URL)
+	return proxyURL, proxy
+}
+
+func TestProxyTunnelsRequests(t *testing.T) {
+	tlsServer, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.RequestURI)
+	}))
+	defer plainServer.Close()
+
+	proxyURL, proxy := newConnectProxy(t, 0)
+	proxyURL.User = url.UserPassword("user", "secret")
+	c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots, Proxy: ProxyURL(proxyURL)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Get(tlsServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "HTTP/2.0" {
+		t.Errorf("tunnelled protocol = %q, want HTTP/2.0", resp.Text)
+	}
+	if resp.Fingerprint == nil || resp.Fingerprint.JA4 == "" {
+		t.Error("tunnelled response has no fingerprint")
+	}
+	resp, err = c.Get(plainServer.URL + "/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "/plain" {
+		t.Errorf("plain request URI = %q, want origin-form /plain", resp.Text)
+	}
+
+	wantTargets := []string{
+		"CONNECT " + strings.TrimPrefix(tlsServer.URL, "https://"),
+		"CONNECT " + strings.TrimPrefix(plainServer.URL, "http://"),
+	}
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if strings.Join(proxy.targets, ",") != strings.Join(wantTargets, ",") {
+		t.Errorf("proxy saw %q, want %q", proxy.targets, wantTargets)
+	}
+	for i := range proxy.agents {
+		if proxy.agents[i] != c.profile.UserAgent {
+			t.Errorf("CONNECT user agent = %q, want the profile's", proxy.agents[i])
+		}
+		if proxy.auths[i] != wantAuth {
+			t.Errorf("Proxy-Authorization = %q, want %q", proxy.auths[i], wantAuth)
+		}
+	}
+}
+
+func TestProxyErrors(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedURL := &url.URL{Scheme: "http", Host: closed.Addr().String()}
+	closed.Close()
+	rejecting, _ := newConnectProxy(t, http.StatusProxyAuthRequired)
+
+	tests := []struct {
+		name   string
+		proxy  *url.URL
+		target string
+		check  func(t *testing.T, err error)
+	}{
+		{"refused https target", closedURL, "https://example.com", func(t *testing.T, err error) {
+			if !errors.Is(err, syscall.ECONNREFUSED) {
+				t.Errorf("error %v does not wrap ECONNREFUSED", err)
+			}
+		}},
+		{"refused plain target", closedURL, "http://example.com", func(t *testing.T, err error) {
+			if !errors.Is(err, syscall.ECONNREFUSED) {
+				t.Errorf("error %v does not wrap ECONNREFUSED", err)
+			}
+		}},
+		{"connect rejected", rejecting, "https://example.com", func(t *testing.T, err error) {
+			if !strings.Contains(err.Error(), "407") {
+				t.Errorf("error %v does not report the 407 status", err)
+			}
+		}},
+		{"unsupported scheme", &url.URL{Scheme: "ftp", Host: "127.0.0.1:21"}, "https://example.com", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewWithOptions("Chrome138", &ClientOptions{Proxy: ProxyURL(tt.proxy)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Get(tt.target)
+
+			var proxyErr *ProxyError
+			if !errors.As(err, &proxyErr) {
+				t.Fatalf("error = %v (%T), want *ProxyError", err, err)
+			}
+			if proxyErr.Proxy != tt.proxy.Redacted() {
+				t.Errorf("proxy = %q, want %q", proxyErr.Proxy, tt.proxy.Redacted())
+			}
+			var handshakeErr *HandshakeError
+			if errors.As(err, &handshakeErr) {
+				t.Error("proxy failure was also reported as a handshake error")
+			}
+			if tt.check != nil {
+				tt.check(t, err)
+			}
+		})
+	}
+}
+
+func TestProxyRejectsHTTP3(t *testing.T) {
+	proxyURL, _ := url.Parse("http://127.0.0.1:8080")
+	if _, err := NewWithOptions("Chrome138", &ClientOptions{HTTP3: true, Proxy: ProxyURL(proxyURL)}); err == nil {
+		t.Fatal("NewWithOptions accepted a proxy together with HTTP3")
+	}
+}
+
+func TestDialErrors(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	c, err := NewWithOptions("Chrome138", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("connection refused", func(t *testing.T) {
+		_, err := c.Get("https://" + closedAddr)
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Fatalf("error = %v, want ECONNREFUSED", err)
+		}
+		var handshakeErr *HandshakeError
+		var timeoutErr *TimeoutError
+		if errors.As(err, &handshakeErr) || errors.As(err, &timeoutErr) {
+			t.Errorf("refused connection classified as %T", err)
+		}
+	})
+
+	t.Run("dns failure", func(t *testing.T) {
+		_, err := c.Get("https://nonexistent.invalid")
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) {
+			t.Fatalf("error = %v (%T), want *net.DNSError in the chain", err, err)
+		}
+		if dnsErr.IsTimeout {
+			t.Skip("no DNS server reachable")
+		}
+		if !dnsErr.IsNotFound {
+			t.Errorf("DNS error %v is not a not-found error", dnsErr)
+		}
+		var handshakeErr *HandshakeError
+		var timeoutErr *TimeoutError
+		if errors.As(err, &handshakeErr) || errors.As(err, &timeoutErr) {
+			t.Errorf("DNS failure classified as %T", err)
+		}
+	})
+}
+
+func newHTTP2FailingServer(t *testing.T, fail func(framer *http2.Framer, streamID uint32)) (string, *http2HeaderRecorder) {
+	t.Helper()
+	template, roots := newTLSServer(t, http.NotFoundHandler())
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: template.TLS.Certificates,
+		NextProtos:   []string{"h2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := io.ReadFull(conn, make([]byte, len(http2.ClientPreface))); err != nil {
+			return
+		}
+
+		framer := http2.NewFramer(conn, conn)
+		framer.WriteSettings()
+		for {
+			frame, err := framer.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch frame := frame.(type) {
+			case *http2.SettingsFrame:
+				if !frame.IsAck() {
+					framer.WriteSettingsAck()
+				}
+			case *http2.HeadersFrame:
+				fail(framer, frame.StreamID)
+				return
+			}
+		}
+	}()
+	return "https://" + listener.Addr().String(), &http2HeaderRecorder{roots: roots}
+}
+
+func TestHTTP2ProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     func(framer *http2.Framer, streamID uint32)
+		wantCode string
+		goAway   bool
+	}{
+		{"stream reset", func(framer *http2.Framer, streamID uint32) {
+			framer.WriteRSTStream(streamID, http2.ErrCodeEnhanceYourCalm)
+		}, "ENHANCE_YOUR_CALM", false},
+		{"goaway", func(framer *http2.Framer, streamID uint32) {
+			framer.WriteGoAway(streamID, http2.ErrCodeProtocol, []byte("bye"))
+		}, "PROTOCOL_ERROR", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, recorder := newHTTP2FailingServer(t, tt.fail)
+			c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: recorder.roots})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Get(endpoint)
+
+			var protoErr *ProtocolError
+			if !errors.As(err, &protoErr) {
+				t.Fatalf("error = %v (%T), want *ProtocolError", err, err)
+			}
+			if protoErr.Protocol != "h2" || protoErr.Code != tt.wantCode || protoErr.GoAway != tt.goAway {
+				t.Errorf("got %s %s goaway=%v, want h2 %s goaway=%v",
+					protoErr.Protocol, protoErr.Code, protoErr.GoAway, tt.wantCode, tt.goAway)
+			}
+			if protoErr.Fingerprint == nil {
+				t.Error("protocol error did not carry the fingerprint")
+			}
+		})
+	}
+}
+
+func TestClassifiesBundledHTTP2Errors(t *testing.T) {
+	c, err := NewWithOptions("Chrome138", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ := url.Parse("https://example.com")
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+		goAway   bool
+	}{
+		{"stream", http2.StreamError{StreamID: 1, Code: http2.ErrCodeRefusedStream}, "REFUSED_STREAM", false},
+		{"goaway", http2.GoAwayError{ErrCode: http2.ErrCodeEnhanceYourCalm}, "ENHANCE_YOUR_CALM", true},
+		{"connection", http2.ConnectionError(http2.ErrCodeFlowControl), "FLOW_CONTROL_ERROR", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped := &url.Error{Op: "Get", URL: target.String(), Err: tt.err}
+			var protoErr *ProtocolError
+			if !errors.As(c.classifyError(wrapped, target, nil), &protoErr) {
+				t.Fatalf("%v was not classified as a protocol error", tt.err)
+			}
+			if protoErr.Code != tt.wantCode || protoErr.GoAway != tt.goAway {
+				t.Errorf("code %s goaway=%v, want %s goaway=%v", protoErr.Code, protoErr.GoAway, tt.wantCode, tt.goAway)
+			}
+		})
+	}
+
+	plain := errors.New("http2: something unrelated")
+	if got := c.classifyError(plain, target, nil); got != plain {
+		t.Errorf("error text alone was classified as %T", got)
+	}
+}
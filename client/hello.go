@@ -0,0 +1,178 @@
+package client
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"slices"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/refraction-networking/utls/dicttls"
+
+	"github.com/rip-zoyo/orbit-tls/profiles"
+)
+
+const extensionECH uint16 = 65037
+
+var http2SettingNames = []string{
+	"HEADER_TABLE_SIZE",
+	"ENABLE_PUSH",
+	"MAX_CONCURRENT_STREAMS",
+	"INITIAL_WINDOW_SIZE",
+	"MAX_FRAME_SIZE",
+	"MAX_HEADER_LIST_SIZE",
+}
+
+func (c *Client) clientHelloSpec(config *tls.Config) *utls.ClientHelloSpec {
+	spec := &utls.ClientHelloSpec{
+		CipherSuites:       append([]uint16(nil), c.profile.CipherSuites...),
+		CompressionMethods: []uint8{0},
+		TLSVersMin:         c.profile.TLSVersion.Min,
+		TLSVersMax:         c.profile.TLSVersion.Max,
+	}
+
+	echOffered := false
+	for _, id := range c.profile.Extensions {
+		ext := c.helloExtension(id, config)
+		if ext == nil {
+			continue
+		}
+		echOffered = echOffered || id == extensionECH
+		spec.Extensions = append(spec.Extensions, ext)
+	}
+	if !echOffered && (c.echEnabled() || len(config.EncryptedClientHelloConfigList) > 0) {
+		spec.Extensions = append(spec.Extensions, c.greaseECH())
+	}
+	if !c.options.DisableSessionResumption && c.profile.TLSVersion.Max >= tls.VersionTLS13 {
+		spec.Extensions = append(spec.Extensions, &utls.UtlsPreSharedKeyExtension{})
+	}
+	return spec
+}
+
+func (c *Client) helloExtension(id uint16, config *tls.Config) utls.TLSExtension {
+	switch id {
+	case 0:
+		return &utls.SNIExtension{}
+	case 5:
+		return &utls.StatusRequestExtension{}
+	case 10:
+		curves := make([]utls.CurveID, len(c.profile.CurvePreferences))
+		for i, curve := range c.profile.CurvePreferences {
+			curves[i] = utls.CurveID(curve)
+		}
+		return &utls.SupportedCurvesExtension{Curves: curves}
+	case 11:
+		return &utls.SupportedPointsExtension{SupportedPoints: []uint8{0}}
+	case 13:
+		schemes := make([]utls.SignatureScheme, len(c.profile.SignatureAlgorithms))
+		for i, scheme := range c.profile.SignatureAlgorithms {
+			schemes[i] = utls.SignatureScheme(scheme)
+		}
+		return &utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: schemes}
+	case 16:
+		if len(config.NextProtos) == 0 {
+			return nil
+		}
+		return &utls.ALPNExtension{AlpnProtocols: config.NextProtos}
+	case 18:
+		return &utls.SCTExtension{}
+	case 21:
+		return &utls.UtlsPaddingExtension{GetPaddingLen: utls.BoringPaddingStyle}
+	case 23:
+		return &utls.ExtendedMasterSecretExtension{}
+	case 27:
+		if len(c.profile.CertCompressionAlgorithms) == 0 {
+			return nil
+		}
+		algorithms := make([]utls.CertCompressionAlgo, len(c.profile.CertCompressionAlgorithms))
+		for i, algorithm := range c.profile.CertCompressionAlgorithms {
+			algorithms[i] = utls.CertCompressionAlgo(algorithm)
+		}
+		return &utls.UtlsCompressCertExtension{Algorithms: algorithms}
+	case 28:
+		return &utls.FakeRecordSizeLimitExtension{Limit: 0x4001}
+	case 35:
+		return &utls.SessionTicketExtension{}
+	case 43:
+		var versions []uint16
+		for version := c.profile.TLSVersion.Max; version >= c.profile.TLSVersion.Min && version >= tls.VersionTLS10; version-- {
+			versions = append(versions, version)
+		}
+		return &utls.SupportedVersionsExtension{Versions: versions}
+	case 45:
+		return &utls.PSKKeyExchangeModesExtension{Modes: []uint8{utls.PskModeDHE}}
+	case 51:
+		return &utls.KeyShareExtension{KeyShares: c.keyShares()}
+	case extensionECH:
+		return c.greaseECH()
+	case profiles.ALPSCodepointLegacy:
+		if protocols := c.alpsProtocols(config); len(protocols) > 0 {
+			return &utls.ApplicationSettingsExtension{SupportedProtocols: protocols}
+		}
+	case profiles.ALPSCodepoint:
+		if protocols := c.alpsProtocols(config); len(protocols) > 0 {
+			return &utls.ApplicationSettingsExtensionNew{SupportedProtocols: protocols}
+		}
+	case 65281:
+		return &utls.RenegotiationInfoExtension{Renegotiation: utls.RenegotiateOnceAsClient}
+	}
+	return nil
+}
+
+func (c *Client) alpsProtocols(config *tls.Config) []string {
+	var protocols []string
+	for _, protocol := range c.profile.ALPSProtocols {
+		if slices.Contains(config.NextProtos, protocol) {
+			protocols = append(protocols, protocol)
+		}
+	}
+	return protocols
+}
+
+func (c *Client) applicationSettings() map[string][]byte {
+	if c.profile.ALPSCodepoint == 0 {
+		return nil
+	}
+
+	var payload []byte
+	for id, name := range http2SettingNames {
+		if value, ok := c.profile.HTTP2Settings[name]; ok {
+			payload = binary.BigEndian.AppendUint16(payload, uint16(id+1))
+			payload = binary.BigEndian.AppendUint32(payload, value)
+		}
+	}
+
+	settings := make(map[string][]byte, len(c.profile.ALPSProtocols))
+	for _, protocol := range c.profile.ALPSProtocols {
+		settings[protocol] = payload
+	}
+	return settings
+}
+
+func (c *Client) keyShares() []utls.KeyShare {
+	curves := c.profile.CurvePreferences
+	if len(curves) == 0 {
+		return []utls.KeyShare{{Group: utls.X25519}}
+	}
+
+	shares := []utls.KeyShare{{Group: utls.CurveID(curves[0])}}
+	switch {
+	case curves[0] == tls.X25519MLKEM768:
+		shares = append(shares, utls.KeyShare{Group: utls.X25519})
+	case c.profile.Family == profiles.FamilyFirefox && len(curves) > 1:
+		shares = append(shares, utls.KeyShare{Group: utls.CurveID(curves[1])})
+	}
+	return shares
+}
+
+func (c *Client) greaseECH() *utls.GREASEEncryptedClientHelloExtension {
+	if c.profile.Family == profiles.FamilyFirefox {
+		return &utls.GREASEEncryptedClientHelloExtension{
+			CandidateCipherSuites: []utls.HPKESymmetricCipherSuite{
+				{KdfId: dicttls.HKDF_SHA256, AeadId: dicttls.AEAD_AES_128_GCM},
+				{KdfId: dicttls.HKDF_SHA256, AeadId: dicttls.AEAD_CHACHA20_POLY1305},
+			},
+			CandidatePayloadLens: []uint16{223},
+		}
+	}
+	return utls.BoringGREASEECH()
+}
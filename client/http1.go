@@ -0,0 +1,219 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	http1StateHead = iota
+	http1StateBody
+	http1StateChunkSize
+	http1StateChunkData
+	http1StateTrailer
+)
+
+type headerOrderConn struct {
+	net.Conn
+	order     map[string]int
+	state     int
+	pending   []byte
+	remaining int64
+}
+
+func newHeaderOrderConn(conn net.Conn, headerOrder []string) *headerOrderConn {
+	order := map[string]int{"host": -2, "connection": -1}
+	for i, name := range headerOrder {
+		if strings.HasPrefix(name, ":") {
+			continue
+		}
+		order[strings.ToLower(name)] = i
+	}
+	return &headerOrderConn{Conn: conn, order: order}
+}
+
+func (c *headerOrderConn) ConnectionState() tls.ConnectionState {
+	if conn, ok := c.Conn.(tlsConn); ok {
+		return conn.ConnectionState()
+	}
+	return tls.ConnectionState{}
+}
+
+func (c *headerOrderConn) Write(p []byte) (int, error) {
+	if err := c.process(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *headerOrderConn) process(p []byte) error {
+	for len(p) > 0 {
+		switch c.state {
+		case http1StateHead:
+			c.pending = append(c.pending, p...)
+			p = nil
+
+			end := bytes.Index(c.pending, []byte("\r\n\r\n"))
+			if end < 0 {
+				continue
+			}
+			head := c.pending[:end+4]
+			rest := append([]byte(nil), c.pending[end+4:]...)
+			c.pending = nil
+
+			reordered, remaining, chunked := c.reorderHead(head)
+			if _, err := c.Conn.Write(reordered); err != nil {
+				return err
+			}
+
+			switch {
+			case chunked:
+				c.state = http1StateChunkSize
+			case remaining > 0:
+				c.state = http1StateBody
+				c.remaining = remaining
+			}
+			p = rest
+
+		case http1StateBody, http1StateChunkData:
+			n := int64(len(p))
+			if n > c.remaining {
+				n = c.remaining
+			}
+			if _, err := c.Conn.Write(p[:n]); err != nil {
+				return err
+			}
+			p = p[n:]
+			c.remaining -= n
+			if c.remaining == 0 {
+				if c.state == http1StateBody {
+					c.state = http1StateHead
+				} else {
+					c.state = http1StateChunkSize
+				}
+			}
+
+		case http1StateChunkSize, http1StateTrailer:
+			line := append(c.pending, p...)
+			end := bytes.Index(line, []byte("\r\n"))
+			if end < 0 {
+				if _, err := c.Conn.Write(p); err != nil {
+					return err
+				}
+				c.pending = line
+				p = nil
+				continue
+			}
+
+			consumed := end + 2 - len(c.pending)
+			if _, err := c.Conn.Write(p[:consumed]); err != nil {
+				return err
+			}
+			p = p[consumed:]
+			line = line[:end]
+			c.pending = nil
+
+			if c.state == http1StateTrailer {
+				if len(line) == 0 {
+					c.state = http1StateHead
+				}
+				continue
+			}
+
+			sizeField, _, _ := strings.Cut(string(line), ";")
+			size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+			if err != nil {
+				c.state = http1StateHead
+				continue
+			}
+			if size == 0 {
+				c.state = http1StateTrailer
+			} else {
+				c.state = http1StateChunkData
+				c.remaining = size + 2
+			}
+		}
+	}
+	return nil
+}
+
+func (c *headerOrderConn) reorderHead(head []byte) ([]byte, int64, bool) {
+	lines := strings.Split(strings.TrimSuffix(string(head), "\r\n\r\n"), "\r\n")
+	if len(lines) == 0 {
+		return head, 0, false
+	}
+
+	fields := joinCookies(lines[1:])
+	var contentLength int64
+	chunked := false
+
+	for i, field := range fields {
+		name, value, found := strings.Cut(field, ":")
+		if !found {
+			continue
+		}
+		lower := strings.ToLower(name)
+		value = strings.TrimSpace(value)
+
+		switch lower {
+		case "content-length":
+			contentLength, _ = strconv.ParseInt(value, 10, 64)
+		case "transfer-encoding":
+			chunked = strings.Contains(strings.ToLower(value), "chunked")
+		}
+
+		if strings.HasPrefix(lower, "sec-ch-") {
+			fields[i] = lower + ": " + value
+		}
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return c.rank(fields[i]) < c.rank(fields[j])
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString(lines[0])
+	buf.WriteString("\r\n")
+	for _, field := range fields {
+		buf.WriteString(field)
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("\r\n")
+
+	return buf.Bytes(), contentLength, chunked
+}
+
+func (c *headerOrderConn) rank(field string) int {
+	name, _, _ := strings.Cut(field, ":")
+	if index, exists := c.order[strings.ToLower(name)]; exists {
+		return index
+	}
+	return math.MaxInt
+}
+
+func joinCookies(fields []string) []string {
+	var cookies []string
+	first := -1
+	joined := make([]string, 0, len(fields))
+	for _, field := range fields {
+		name, value, found := strings.Cut(field, ":")
+		if !found || !strings.EqualFold(name, "cookie") {
+			joined = append(joined, field)
+			continue
+		}
+		if first < 0 {
+			first = len(joined)
+			joined = append(joined, field)
+		}
+		cookies = append(cookies, strings.TrimSpace(value))
+	}
+	if len(cookies) > 1 {
+		joined[first] = "Cookie: " + strings.Join(cookies, "; ")
+	}
+	return joined
+}
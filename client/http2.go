@@ -0,0 +1,234 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+const (
+	http2FrameHeaders      = 0x1
+	http2FrameContinuation = 0x9
+
+	http2FlagEndHeaders = 0x4
+	http2FlagPadded     = 0x8
+	http2FlagPriority   = 0x20
+
+	http2FrameHeaderLen = 9
+	http2MaxFrameSize   = 16384
+)
+
+var http2Preface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+type tlsConn interface {
+	net.Conn
+	ConnectionState() tls.ConnectionState
+}
+
+type http2HeaderConn struct {
+	tlsConn
+	prefaceDone bool
+	pending     []byte
+
+	pseudoOrder  []string
+	rank         map[string]int
+	mergeCookies bool
+
+	decoder *hpack.Decoder
+	encoder *hpack.Encoder
+	encoded bytes.Buffer
+
+	stream   uint32
+	flags    byte
+	priority []byte
+	block    []byte
+}
+
+func newHTTP2HeaderConn(conn tlsConn, pseudoOrder, headerOrder []string, mergeCookies bool) *http2HeaderConn {
+	c := &http2HeaderConn{
+		tlsConn:      conn,
+		pseudoOrder:  slices.Concat(pseudoOrder, defaultPseudoHeaderOrder),
+		rank:         make(map[string]int, len(headerOrder)),
+		mergeCookies: mergeCookies,
+		decoder:      hpack.NewDecoder(4096, nil),
+	}
+	for i, name := range headerOrder {
+		if !strings.HasPrefix(name, ":") {
+			c.rank[strings.ToLower(name)] = i
+		}
+	}
+	c.encoder = hpack.NewEncoder(&c.encoded)
+	c.encoder.SetMaxDynamicTableSizeLimit(0)
+	return c
+}
+
+func (c *http2HeaderConn) Write(p []byte) (int, error) {
+	c.pending = append(c.pending, p...)
+
+	var out []byte
+	if !c.prefaceDone {
+		if len(c.pending) < len(http2Preface) {
+			return len(p), nil
+		}
+		out = append(out, c.pending[:len(http2Preface)]...)
+		c.pending = c.pending[len(http2Preface):]
+		c.prefaceDone = true
+	}
+
+	for len(c.pending) >= http2FrameHeaderLen {
+		length := int(c.pending[0])<<16 | int(c.pending[1])<<8 | int(c.pending[2])
+		if len(c.pending) < http2FrameHeaderLen+length {
+			break
+		}
+		frame := c.pending[:http2FrameHeaderLen+length]
+		c.pending = c.pending[http2FrameHeaderLen+length:]
+
+		rewritten, err := c.rewrite(frame)
+		if err != nil {
+			return 0, err
+		}
+		out = append(out, rewritten...)
+	}
+
+	if len(out) > 0 {
+		if _, err := c.tlsConn.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (c *http2HeaderConn) rewrite(frame []byte) ([]byte, error) {
+	frameType, flags := frame[3], frame[4]
+	payload := frame[http2FrameHeaderLen:]
+
+	switch frameType {
+	case http2FrameHeaders:
+		if flags&http2FlagPadded != 0 {
+			if len(payload) == 0 || int(payload[0]) >= len(payload) {
+				return nil, errors.New("http2: invalid padded HEADERS frame")
+			}
+			payload = payload[1 : len(payload)-int(payload[0])]
+		}
+		c.priority = nil
+		if flags&http2FlagPriority != 0 {
+			if len(payload) < 5 {
+				return nil, errors.New("http2: invalid HEADERS priority")
+			}
+			c.priority = append(c.priority, payload[:5]...)
+			payload = payload[5:]
+		}
+		c.stream = binary.BigEndian.Uint32(frame[5:9]) & 0x7fffffff
+		c.flags = flags &^ (http2FlagPadded | http2FlagEndHeaders)
+		c.block = append(c.block[:0], payload...)
+	case http2FrameContinuation:
+		c.block = append(c.block, payload...)
+	default:
+		return frame, nil
+	}
+
+	if flags&http2FlagEndHeaders == 0 {
+		return nil, nil
+	}
+	return c.encodeHeaders()
+}
+
+func (c *http2HeaderConn) encodeHeaders() ([]byte, error) {
+	fields, err := c.decoder.DecodeFull(c.block)
+	if err != nil {
+		return nil, err
+	}
+
+	fields = c.orderFields(fields)
+	if c.mergeCookies {
+		fields = mergeCookieFields(fields)
+	}
+
+	c.encoded.Reset()
+	for _, field := range fields {
+		if err := c.encoder.WriteField(field); err != nil {
+			return nil, err
+		}
+	}
+	block := c.encoded.Bytes()
+
+	var out []byte
+	frameType, flags, payload := byte(http2FrameHeaders), c.flags, c.priority
+	for {
+		n := min(len(block), http2MaxFrameSize-len(payload))
+		payload = append(payload, block[:n]...)
+		block = block[n:]
+		if len(block) == 0 {
+			flags |= http2FlagEndHeaders
+		}
+		out = appendHTTP2Frame(out, frameType, flags, c.stream, payload)
+		if len(block) == 0 {
+			return out, nil
+		}
+		frameType, flags, payload = http2FrameContinuation, 0, nil
+	}
+}
+
+func (c *http2HeaderConn) orderFields(fields []hpack.HeaderField) []hpack.HeaderField {
+	ordered := slices.Clone(fields)
+	slices.SortStableFunc(ordered, func(a, b hpack.HeaderField) int {
+		pseudoA, pseudoB := strings.HasPrefix(a.Name, ":"), strings.HasPrefix(b.Name, ":")
+		switch {
+		case pseudoA && pseudoB:
+			return pseudoRank(c.pseudoOrder, a.Name) - pseudoRank(c.pseudoOrder, b.Name)
+		case pseudoA != pseudoB:
+			if pseudoA {
+				return -1
+			}
+			return 1
+		}
+		if rankA, rankB := headerRank(c.rank, a.Name), headerRank(c.rank, b.Name); rankA != rankB {
+			if rankA < rankB {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return ordered
+}
+
+func pseudoRank(order []string, name string) int {
+	if index := slices.Index(order, name); index >= 0 {
+		return index
+	}
+	return len(order)
+}
+
+func appendHTTP2Frame(b []byte, frameType, flags byte, stream uint32, payload []byte) []byte {
+	b = append(b, byte(len(payload)>>16), byte(len(payload)>>8), byte(len(payload)), frameType, flags)
+	b = binary.BigEndian.AppendUint32(b, stream)
+	return append(b, payload...)
+}
+
+func mergeCookieFields(fields []hpack.HeaderField) []hpack.HeaderField {
+	var cookies []string
+	first := -1
+	merged := make([]hpack.HeaderField, 0, len(fields))
+	for _, field := range fields {
+		if field.Name != "cookie" {
+			merged = append(merged, field)
+			continue
+		}
+		if first < 0 {
+			first = len(merged)
+			merged = append(merged, field)
+		}
+		cookies = append(cookies, field.Value)
+	}
+	if len(cookies) > 1 {
+		merged[first].Value = strings.Join(cookies, "; ")
+	}
+	return merged
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func newHTTP2HeaderServer(t *testing.T) (string, *http2HeaderRecorder) {
+	t.Helper()
+	template, roots := newTLSServer(t, http.NotFoundHandler())
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: template.TLS.Certificates,
+		NextProtos:   []string{"h2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	recorder := &http2HeaderRecorder{roots: roots, fields: make(chan []hpack.HeaderField, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := io.ReadFull(conn, make([]byte, len(http2.ClientPreface))); err != nil {
+			return
+		}
+
+		framer := http2.NewFramer(conn, conn)
+		framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+		framer.WriteSettings()
+		for {
+			frame, err := framer.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch frame := frame.(type) {
+			case *http2.SettingsFrame:
+				if !frame.IsAck() {
+					framer.WriteSettingsAck()
+				}
+			case *http2.MetaHeadersFrame:
+				recorder.fields <- frame.Fields
+				var block bytes.Buffer
+				hpack.NewEncoder(&block).WriteField(hpack.HeaderField{Name: ":status", Value: "204"})
+				framer.WriteHeaders(http2.HeadersFrameParam{
+					StreamID:      frame.StreamID,
+					BlockFragment: block.Bytes(),
+					EndStream:     true,
+					EndHeaders:    true,
+				})
+			}
+		}
+	}()
+	return "https://" + listener.Addr().String(), recorder
+}
+
+type http2HeaderRecorder struct {
+	roots  *x509.CertPool
+	fields chan []hpack.HeaderField
+}
+
+func TestHTTP2CookieFields(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		{"Chrome138", []string{"a=1", "b=2", "c=3"}},
+		{"Firefox131", []string{"a=1", "b=2", "c=3"}},
+		{"Safari18", []string{"a=1; b=2; c=3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			endpoint, recorder := newHTTP2HeaderServer(t)
+			c, err := NewWithOptions(tt.profile, &ClientOptions{RootCAs: recorder.roots})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Get(endpoint, map[string]string{"Cookie": "a=1; b=2; c=3"}); err != nil {
+				t.Fatal(err)
+			}
+
+			var cookies, names []string
+			for _, field := range <-recorder.fields {
+				names = append(names, field.Name)
+				if field.Name == "cookie" {
+					cookies = append(cookies, field.Value)
+				}
+			}
+			if !slices.Equal(cookies, tt.want) {
+				t.Errorf("cookie fields = %q, want %q", cookies, tt.want)
+			}
+			for _, name := range []string{":method", ":authority", ":scheme", ":path", "user-agent"} {
+				if !slices.Contains(names, name) {
+					t.Errorf("header list %v is missing %s", names, name)
+				}
+			}
+		})
+	}
+}
+
+func TestOSVariantHTTP2Fingerprint(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	akamai := func(profile, os string) string {
+		t.Helper()
+		c, err := NewWithOptions(profile, &ClientOptions{RootCAs: roots, OS: os})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := c.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Fingerprint == nil || resp.Fingerprint.HTTP2 == nil {
+			t.Fatalf("%s on %s: no HTTP/2 fingerprint", profile, os)
+		}
+		return resp.Fingerprint.AkamaiFP
+	}
+
+	safari := akamai("SafariiOS18", "")
+	if got := akamai("Chrome138", "iOS"); got != safari {
+		t.Errorf("Chrome138 on iOS reports %s, want the WebKit fingerprint %s", got, safari)
+	}
+	if got := akamai("Firefox131", "iOS"); got != safari {
+		t.Errorf("Firefox131 on iOS reports %s, want the WebKit fingerprint %s", got, safari)
+	}
+	if got, chrome := akamai("Chrome138", "macOS"), akamai("Chrome138", ""); got != chrome || got == safari {
+		t.Errorf("Chrome138 on macOS reports %s, want the Chrome fingerprint %s", got, chrome)
+	}
+	if got := akamai("Firefox131", ""); got == akamai("Chrome138", "") {
+		t.Errorf("Firefox131 reports the Chrome fingerprint %s", got)
+	}
+}
+
+func TestHTTP2HeaderOrder(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		{"Chrome138", []string{
+			":method", ":authority", ":scheme", ":path",
+			"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests", "user-agent", "accept",
+			"sec-fetch-site", "sec-fetch-mode", "sec-fetch-user", "sec-fetch-dest", "accept-encoding", "accept-language", "priority",
+			"authorization", "cookie", "cookie", "x-custom",
+		}},
+		{"Firefox131", []string{
+			":method", ":path", ":authority", ":scheme",
+			"user-agent", "accept", "accept-language", "accept-encoding", "upgrade-insecure-requests",
+			"sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site", "sec-fetch-user", "priority",
+			"authorization", "cookie", "cookie", "x-custom",
+		}},
+		{"Safari18", []string{
+			":method", ":scheme", ":path", ":authority",
+			"user-agent", "accept", "accept-language", "accept-encoding", "upgrade-insecure-requests",
+			"sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "priority",
+			"authorization", "cookie", "x-custom",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			endpoint, recorder := newHTTP2HeaderServer(t)
+			c, err := NewWithOptions(tt.profile, &ClientOptions{RootCAs: recorder.roots})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Get(endpoint, map[string]string{
+				"X-Custom":      "1",
+				"Authorization": "Bearer token",
+				"Cookie":        "a=1; b=2",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, field := range <-recorder.fields {
+				names = append(names, field.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("header order = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
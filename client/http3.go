@@ -0,0 +1,598 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/rip-zoyo/orbit-tls/profiles"
+	"github.com/rip-zoyo/orbit-tls/tracking"
+)
+
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+type altSvcEntry struct {
+	authority string
+	expires   time.Time
+}
+
+type altSvcCache struct {
+	mu      sync.Mutex
+	entries map[string]altSvcEntry
+}
+
+type altSvcTransport struct {
+	tcp       http.RoundTripper
+	h3        *h3Transport
+	cache     *altSvcCache
+	earlyData bool
+}
+
+func newAltSvcCache() *altSvcCache {
+	return &altSvcCache{
+		entries: make(map[string]altSvcEntry),
+	}
+}
+
+func (c *altSvcCache) lookup(origin string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[origin]
+	if !exists {
+		return "", false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, origin)
+		return "", false
+	}
+	return entry.authority, true
+}
+
+func (c *altSvcCache) store(origin, authority string, maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[origin] = altSvcEntry{authority: authority, expires: time.Now().Add(maxAge)}
+}
+
+func (c *altSvcCache) remove(origin string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, origin)
+}
+
+func parseAltSvc(value, host string) (string, time.Duration, bool) {
+	for _, service := range strings.Split(value, ",") {
+		params := strings.Split(service, ";")
+		protocol, authority, found := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !found || protocol != "h3" {
+			continue
+		}
+
+		authority = strings.Trim(authority, `"`)
+		altHost, altPort, err := net.SplitHostPort(authority)
+		if err != nil {
+			continue
+		}
+		if altHost == "" {
+			altHost = host
+		}
+
+		maxAge := defaultAltSvcMaxAge
+		for _, param := range params[1:] {
+			key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key != "ma" {
+				continue
+			}
+			if seconds, err := strconv.ParseInt(val, 10, 64); err == nil {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+
+		return net.JoinHostPort(altHost, altPort), maxAge, true
+	}
+	return "", 0, false
+}
+
+func canonicalOrigin(req *http.Request) string {
+	if req.URL.Port() != "" {
+		return req.URL.Host
+	}
+	return net.JoinHostPort(req.URL.Hostname(), "443")
+}
+
+func (t *altSvcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "https" {
+		origin := canonicalOrigin(req)
+		if _, ok := t.cache.lookup(origin); ok {
+			h3Req := req
+			if t.earlyData {
+				h3Req = earlyDataRequest(req)
+			}
+			resp, err := t.h3.RoundTrip(h3Req)
+			if err == nil {
+				t.remember(req, resp)
+				return resp, nil
+			}
+
+			t.cache.remove(origin)
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return nil, err
+				}
+				body, bodyErr := req.GetBody()
+				if bodyErr != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+		}
+	}
+
+	resp, err := t.tcp.RoundTrip(req)
+	if err == nil {
+		t.remember(req, resp)
+	}
+	return resp, err
+}
+
+func (t *altSvcTransport) remember(req *http.Request, resp *http.Response) {
+	if req.URL.Scheme != "https" {
+		return
+	}
+
+	value := resp.Header.Get("Alt-Svc")
+	if value == "" {
+		return
+	}
+
+	origin := canonicalOrigin(req)
+	if strings.TrimSpace(value) == "clear" {
+		t.cache.remove(origin)
+		return
+	}
+
+	if authority, maxAge, ok := parseAltSvc(value, req.URL.Hostname()); ok && maxAge > 0 {
+		t.cache.store(origin, authority, maxAge)
+	}
+}
+
+func (t *altSvcTransport) CloseIdleConnections() {
+	if closer, ok := t.tcp.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+	t.h3.CloseIdleConnections()
+}
+
+type h3Transport struct {
+	client     *Client
+	cache      *altSvcCache
+	tlsConfig  *tls.Config
+	quicConfig *quic.Config
+	settings   *http3.Transport
+
+	disableKeepAlives bool
+
+	mu    sync.Mutex
+	conns map[string]*h3Conn
+}
+
+type h3Conn struct {
+	addr   string
+	ready  chan struct{}
+	conn   *quic.Conn
+	client *http3.ClientConn
+	live   *liveConn
+	err    error
+
+	recordOnce sync.Once
+	details    *tracking.ConnectionDetails
+}
+
+type quicConn struct {
+	*net.UDPConn
+	conn *quic.Conn
+}
+
+func (q *quicConn) RemoteAddr() net.Addr {
+	return q.conn.RemoteAddr()
+}
+
+func (q *quicConn) Close() error {
+	err := q.conn.CloseWithError(quic.ApplicationErrorCode(http3.ErrCodeNoError), "")
+	q.UDPConn.Close()
+	return err
+}
+
+func (c *Client) newHTTP3Transport(cache *altSvcCache, disableKeepAlives bool) *h3Transport {
+	tlsConfig := c.tlsConfig.Clone()
+	tlsConfig.MinVersion = tls.VersionTLS13
+	tlsConfig.NextProtos = []string{http3.NextProtoH3}
+
+	return &h3Transport{
+		client:     c,
+		cache:      cache,
+		tlsConfig:  tlsConfig,
+		quicConfig: quicConfigForProfile(c.profile),
+		settings:   &http3.Transport{MaxResponseHeaderBytes: http3MaxFieldSectionSize},
+		conns:      make(map[string]*h3Conn),
+
+		disableKeepAlives: disableKeepAlives,
+	}
+}
+
+func (t *h3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	early := false
+	switch req.Method {
+	case http3.MethodGet0RTT, http3.MethodHead0RTT:
+		method := http.MethodGet
+		if req.Method == http3.MethodHead0RTT {
+			method = http.MethodHead
+		}
+		copied := *req
+		copied.Method = method
+		req = &copied
+		early = true
+	}
+
+	ctx := req.Context()
+	addr := canonicalOrigin(req)
+	conn, reused, err := t.getConn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if !early {
+		select {
+		case <-conn.conn.HandshakeComplete():
+		case <-ctx.Done():
+			if t.disableKeepAlives {
+				conn.live.Close()
+			}
+			return nil, ctx.Err()
+		}
+		t.record(conn)
+	}
+
+	if trace := httptrace.ContextClientTrace(ctx); trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: conn.live, Reused: reused})
+	}
+
+	resp, err := t.doRequest(conn, req)
+	if err != nil {
+		if t.disableKeepAlives {
+			conn.live.Close()
+		} else if conn.conn.Context().Err() != nil {
+			t.remove(addr, conn)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, http3Error(err)
+	}
+	return resp, nil
+}
+
+func (t *h3Transport) doRequest(conn *h3Conn, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	str, err := conn.conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			str.CancelWrite(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+			str.CancelRead(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		case <-done:
+		}
+	}()
+	var finish sync.Once
+	stop := func() { finish.Do(func() { close(done) }) }
+
+	fields := t.client.http3Fields(req)
+	trace := httptrace.ContextClientTrace(ctx)
+	if req.Body == nil || req.Body == http.NoBody {
+		err := writeHTTP3Request(str, req, fields)
+		if trace != nil && trace.WroteRequest != nil {
+			trace.WroteRequest(httptrace.WroteRequestInfo{Err: err})
+		}
+		if err != nil {
+			stop()
+			return nil, err
+		}
+	} else {
+		go func() {
+			err := writeHTTP3Request(str, req, fields)
+			if trace != nil && trace.WroteRequest != nil {
+				trace.WroteRequest(httptrace.WroteRequestInfo{Err: err})
+			}
+		}()
+	}
+
+	resp, err := readHTTP3Response(str, req)
+	if err != nil {
+		str.CancelRead(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		str.CancelWrite(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		stop()
+		return nil, err
+	}
+	state := conn.conn.ConnectionState().TLS
+	resp.TLS = &state
+	resp.Body.(*http3Body).onClose = stop
+	if t.disableKeepAlives {
+		resp.Body.(*http3Body).onClose = func() {
+			stop()
+			conn.live.Close()
+		}
+	}
+	return resp, nil
+}
+
+func (t *h3Transport) getConn(ctx context.Context, addr string) (*h3Conn, bool, error) {
+	if t.disableKeepAlives {
+		conn := &h3Conn{addr: addr, ready: make(chan struct{})}
+		if err := t.connect(ctx, conn); err != nil {
+			return nil, false, err
+		}
+		return conn, false, nil
+	}
+
+	t.mu.Lock()
+	conn, ok := t.conns[addr]
+	if ok && conn.conn != nil && conn.conn.Context().Err() != nil {
+		delete(t.conns, addr)
+		ok = false
+	}
+	if ok {
+		t.mu.Unlock()
+		select {
+		case <-conn.ready:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		if conn.err != nil {
+			return nil, false, conn.err
+		}
+		return conn, true, nil
+	}
+
+	conn = &h3Conn{addr: addr, ready: make(chan struct{})}
+	t.conns[addr] = conn
+	t.mu.Unlock()
+
+	if err := t.connect(ctx, conn); err != nil {
+		return nil, false, err
+	}
+	return conn, false, nil
+}
+
+func (t *h3Transport) connect(ctx context.Context, conn *h3Conn) error {
+	addr := conn.addr
+	conn.conn, conn.live, conn.err = t.dial(ctx, addr)
+	if conn.err == nil {
+		conn.err = t.verify(ctx, conn)
+	}
+	close(conn.ready)
+	if conn.err != nil {
+		t.remove(addr, conn)
+		return conn.err
+	}
+
+	conn.client = t.settings.NewClientConn(conn.conn)
+	go func() {
+		select {
+		case <-conn.conn.HandshakeComplete():
+			t.record(conn)
+		case <-conn.client.Context().Done():
+		}
+		<-conn.client.Context().Done()
+		t.remove(addr, conn)
+		conn.live.Close()
+	}()
+	return nil
+}
+
+func (t *h3Transport) verify(ctx context.Context, conn *h3Conn) error {
+	c := t.client
+	if len(c.pins) == 0 && c.options.VerifyConnection == nil {
+		return nil
+	}
+
+	select {
+	case <-conn.conn.HandshakeComplete():
+	case <-ctx.Done():
+		conn.live.Close()
+		return ctx.Err()
+	}
+	t.record(conn)
+	if err := c.verifyConnection(conn.conn.ConnectionState().TLS, conn.details); err != nil {
+		conn.live.Close()
+		return &tracking.HandshakeFailure{Addr: conn.addr, Details: conn.details, Err: err}
+	}
+	return nil
+}
+
+func (t *h3Transport) record(conn *h3Conn) {
+	conn.recordOnce.Do(func() {
+		state := conn.conn.ConnectionState()
+		conn.details = t.client.dialer.RecordQUICConnection(conn.addr, conn.live, state.TLS, state.Used0RTT, appliedQUICParameters(t.client.profile))
+	})
+}
+
+func (t *h3Transport) remove(addr string, conn *h3Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns[addr] == conn {
+		delete(t.conns, addr)
+	}
+}
+
+func (t *h3Transport) dial(ctx context.Context, addr string) (*quic.Conn, *liveConn, error) {
+	c := t.client
+	target := addr
+	if authority, ok := t.cache.lookup(addr); ok {
+		target = authority
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetHost, targetPort, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	port, err := strconv.ParseUint(targetPort, 10, 16)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid port %q: %w", targetPort, err)
+	}
+
+	tlsConfig := c.withClientCertificate(t.tlsConfig, host).Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	addrs, resolution, err := c.resolve(ctx, targetHost, targetPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trace := httptrace.ContextClientTrace(ctx)
+	var lastErr error
+	for _, ip := range addrs {
+		remote := net.UDPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(port)))
+		if trace != nil && trace.ConnectStart != nil {
+			trace.ConnectStart("udp", remote.String())
+		}
+		conn, live, err := t.dialAddr(ctx, addr, remote, tlsConfig, resolution)
+		if trace != nil && trace.ConnectDone != nil {
+			trace.ConnectDone("udp", remote.String(), err)
+		}
+		if err == nil {
+			return conn, live, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, nil, lastErr
+}
+
+func (t *h3Transport) dialAddr(ctx context.Context, addr string, remote *net.UDPAddr, tlsConfig *tls.Config, resolution *tracking.Resolution) (*quic.Conn, *liveConn, error) {
+	c := t.client
+	network := "udp4"
+	if remote.IP.To4() == nil {
+		network = "udp6"
+	}
+	pconn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dial := quic.Dial
+	if c.options.EarlyData {
+		dial = quic.DialEarly
+	}
+	conn, err := dial(ctx, pconn, remote, tlsConfig, t.quicConfig)
+	if err != nil {
+		pconn.Close()
+		return nil, nil, err
+	}
+
+	live := c.connections.add(&quicConn{UDPConn: pconn, conn: conn}, addr, resolution)
+	c.connections.bind(live, live)
+	return conn, live, nil
+}
+
+func (t *h3Transport) CloseIdleConnections() {
+	t.mu.Lock()
+	var idle []*h3Conn
+	for addr, conn := range t.conns {
+		select {
+		case <-conn.ready:
+		default:
+			continue
+		}
+		if conn.err == nil && conn.live.idle() {
+			idle = append(idle, conn)
+			delete(t.conns, addr)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, conn := range idle {
+		conn.live.Close()
+	}
+}
+
+func earlyDataRequest(req *http.Request) *http.Request {
+	var method string
+	switch req.Method {
+	case "", http.MethodGet:
+		method = http3.MethodGet0RTT
+	case http.MethodHead:
+		method = http3.MethodHead0RTT
+	default:
+		return req
+	}
+	early := *req
+	early.Method = method
+	return &early
+}
+
+var quicParameterKeys = []string{
+	"initial_max_data",
+	"initial_max_stream_data_bidi_local",
+	"initial_max_streams_bidi",
+	"initial_max_streams_uni",
+	"max_idle_timeout",
+}
+
+func appliedQUICParameters(profile *profiles.Profile) map[string]uint64 {
+	params := make(map[string]uint64)
+	for _, key := range quicParameterKeys {
+		if value, ok := profile.QUICTransportParameters[key]; ok {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+func quicConfigForProfile(profile *profiles.Profile) *quic.Config {
+	params := profile.QUICTransportParameters
+	config := &quic.Config{}
+
+	if value, ok := params["initial_max_data"]; ok {
+		config.InitialConnectionReceiveWindow = value
+		config.MaxConnectionReceiveWindow = max(value, 15*1024*1024)
+	}
+	if value, ok := params["initial_max_stream_data_bidi_local"]; ok {
+		config.InitialStreamReceiveWindow = value
+		config.MaxStreamReceiveWindow = max(value, 6*1024*1024)
+	}
+	if value, ok := params["initial_max_streams_bidi"]; ok {
+		config.MaxIncomingStreams = int64(value)
+	}
+	if value, ok := params["initial_max_streams_uni"]; ok {
+		config.MaxIncomingUniStreams = int64(value)
+	}
+	if value, ok := params["max_idle_timeout"]; ok {
+		config.MaxIdleTimeout = time.Duration(value) * time.Millisecond
+	}
+
+	return config
+}
@@ -0,0 +1,408 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/qpack"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
+
+	"github.com/rip-zoyo/orbit-tls/profiles"
+)
+
+type quicConnKey struct{}
+
+func newHTTP3Server(t *testing.T, handler http.Handler) (string, *x509.CertPool) {
+	t.Helper()
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	altSvc := fmt.Sprintf(`h3=":%d"; ma=60`, udp.LocalAddr().(*net.UDPAddr).Port)
+	tcp, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", altSvc)
+	}))
+
+	server := &http3.Server{
+		Handler:    handler,
+		TLSConfig:  http3.ConfigureTLSConfig(&tls.Config{Certificates: tcp.TLS.Certificates}),
+		QUICConfig: &quic.Config{Allow0RTT: true},
+		ConnContext: func(ctx context.Context, conn *quic.Conn) context.Context {
+			return context.WithValue(ctx, quicConnKey{}, conn)
+		},
+	}
+	go server.Serve(udp)
+	t.Cleanup(func() {
+		server.Close()
+		udp.Close()
+	})
+	return tcp.URL, roots
+}
+
+func TestHTTP3EarlyData(t *testing.T) {
+	endpoint, roots := newHTTP3Server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := r.Context().Value(quicConnKey{}).(*quic.Conn)
+		w.Write([]byte(strconv.FormatBool(conn.ConnectionState().Used0RTT)))
+	}))
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{HTTP3: true, EarlyData: true, RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get(endpoint); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Proto != "HTTP/3.0" || resp.Text != "false" {
+		t.Fatalf("first QUIC request: proto %s, 0-RTT %s; want HTTP/3.0 without 0-RTT", resp.Proto, resp.Text)
+	}
+
+	c.CloseIdle()
+	resp, err = c.Get(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Proto != "HTTP/3.0" || resp.Text != "true" {
+		t.Fatalf("resumed QUIC request: proto %s, 0-RTT %s; want HTTP/3.0 over 0-RTT", resp.Proto, resp.Text)
+	}
+
+	target, _ := url.Parse(endpoint)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		details, ok := c.tracker.GetConnection(target.Host)
+		if ok && details.EarlyData {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tracked details = %+v, want EarlyData", details)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readTestHeaders(str *quic.Stream) ([]qpack.HeaderField, error) {
+	r := quicvarint.NewReader(str)
+	frameType, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	length, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if frameType != http3FrameHeaders {
+		return nil, fmt.Errorf("first frame type %d, want HEADERS", frameType)
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r, block); err != nil {
+		return nil, err
+	}
+
+	var fields []qpack.HeaderField
+	decode := qpack.NewDecoder().Decode(block)
+	for {
+		field, err := decode()
+		if err == io.EOF {
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+}
+
+func TestHTTP3HeaderOrderAndResolution(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { udp.Close() })
+	altSvc := fmt.Sprintf(`h3=":%d"; ma=60`, udp.LocalAddr().(*net.UDPAddr).Port)
+	tcp, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", altSvc)
+	}))
+
+	listener, err := quic.Listen(udp, http3.ConfigureTLSConfig(&tls.Config{Certificates: tcp.TLS.Certificates}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []qpack.HeaderField, 1)
+	go func() {
+		conn, err := listener.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		str, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		fields, err := readTestHeaders(str)
+		if err != nil {
+			t.Error(err)
+			close(received)
+			return
+		}
+		received <- fields
+
+		response, _ := encodeHTTP3Headers([]qpack.HeaderField{{Name: ":status", Value: "200"}})
+		response = appendHTTP3Frame(response, http3FrameData, []byte("ordered"))
+		str.Write(response)
+		str.Close()
+	}()
+
+	target, _ := url.Parse(tcp.URL)
+	c, err := NewWithOptions("Firefox131", &ClientOptions{
+		HTTP3:   true,
+		RootCAs: roots,
+		Hosts:   map[string]string{"example.com": target.Hostname()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := "https://example.com:" + target.Port()
+	if _, err := c.Get(endpoint); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Proto != "HTTP/3.0" || resp.Text != "ordered" {
+		t.Fatalf("got %s %q, want the HTTP/3 response", resp.Proto, resp.Text)
+	}
+
+	fields := <-received
+	profile, _ := profiles.Get("Firefox131")
+	var pseudo, regular []string
+	for _, field := range fields {
+		if field.IsPseudo() {
+			pseudo = append(pseudo, field.Name)
+		} else {
+			regular = append(regular, field.Name)
+		}
+	}
+	if !slices.Equal(pseudo, profile.PseudoHeaderOrder) {
+		t.Errorf("pseudo-header order = %v, want %v", pseudo, profile.PseudoHeaderOrder)
+	}
+	last := -1
+	for _, name := range regular {
+		index := slices.Index(profile.HeaderOrder, name)
+		if index < 0 {
+			continue
+		}
+		if index < last {
+			t.Errorf("header order = %v, want profile order %v", regular, profile.HeaderOrder)
+			break
+		}
+		last = index
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var h3 *ConnectionInfo
+		for _, info := range c.Connections() {
+			if info.Protocol == "h3" {
+				h3 = &info
+			}
+		}
+		if h3 != nil {
+			if h3.Addr != "example.com:"+target.Port() || h3.RemoteAddr != udp.LocalAddr().String() {
+				t.Errorf("h3 connection %s -> %s, want example.com:%s -> %s", h3.Addr, h3.RemoteAddr, target.Port(), udp.LocalAddr())
+			}
+			if h3.Details.Resolution == nil || h3.Details.Resolution.Source != "static" {
+				t.Errorf("h3 resolution = %+v, want the static Hosts entry", h3.Details.Resolution)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("connections = %+v, want an h3 connection", c.Connections())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHTTP3ForceNewConnection(t *testing.T) {
+	endpoint, roots := newHTTP3Server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{HTTP3: true, RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(endpoint); err != nil {
+		t.Fatal(err)
+	}
+
+	var remotes []string
+	for i := 0; i < 2; i++ {
+		resp, err := c.Request("GET", endpoint, nil, &RequestOptions{ForceNewConnection: true})
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if resp.Proto != "HTTP/3.0" {
+			t.Fatalf("request %d: proto %s, want HTTP/3.0", i, resp.Proto)
+		}
+		remotes = append(remotes, resp.Text)
+	}
+	if remotes[0] == remotes[1] {
+		t.Errorf("forced requests shared the QUIC connection from %s", remotes[0])
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		open := 0
+		for _, conn := range c.Connections() {
+			if conn.Protocol == "h3" {
+				open++
+			}
+		}
+		if open == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d QUIC connections still open after the forced requests", open)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHTTP3ResponseValidation(t *testing.T) {
+	headersFrame := func(fields ...qpack.HeaderField) []byte {
+		frame, err := encodeHTTP3Headers(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return frame
+	}
+	status := func(value string) qpack.HeaderField {
+		return qpack.HeaderField{Name: ":status", Value: value}
+	}
+	large := strings.Repeat("a", http3MaxFieldSectionSize/2)
+
+	tests := []struct {
+		name   string
+		frames [][]byte
+		err    string
+	}{
+		{"ok", [][]byte{headersFrame(status("200"))}, ""},
+		{"missing status", [][]byte{headersFrame(qpack.HeaderField{Name: "server", Value: "test"})}, "invalid status"},
+		{"two digits", [][]byte{headersFrame(status("99"))}, "invalid status"},
+		{"four digits", [][]byte{headersFrame(status("2000"))}, "invalid status"},
+		{"above 599", [][]byte{headersFrame(status("600"))}, "invalid status"},
+		{"signed", [][]byte{headersFrame(status("+20"))}, "invalid status"},
+		{"switching protocols", [][]byte{headersFrame(status("101"))}, "invalid status"},
+		{"request pseudo header", [][]byte{headersFrame(status("200"), qpack.HeaderField{Name: ":path", Value: "/"})}, "pseudo header"},
+		{"pseudo after regular", [][]byte{headersFrame(qpack.HeaderField{Name: "server", Value: "test"}, status("200"))}, "pseudo header"},
+		{"oversized section", [][]byte{headersFrame(status("200"),
+			qpack.HeaderField{Name: "x-a", Value: large},
+			qpack.HeaderField{Name: "x-b", Value: large},
+		)}, "exceeds"},
+		{"informational then final", [][]byte{headersFrame(status("103")), headersFrame(status("204"))}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := &http3FrameReader{r: quicvarint.NewReader(bytes.NewReader(slices.Concat(tt.frames...)))}
+			var err error
+			for {
+				frameType, length, nextErr := frames.next()
+				if nextErr != nil {
+					t.Fatal(nextErr)
+				}
+				if frameType != http3FrameHeaders {
+					t.Fatalf("frame type %d, want HEADERS", frameType)
+				}
+				var pseudo map[string]string
+				var code int
+				_, pseudo, err = frames.headers(length)
+				if err == nil {
+					code, err = parseHTTP3Status(pseudo)
+				}
+				if err != nil || code >= 200 {
+					break
+				}
+			}
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestHTTP3TrailerLimits(t *testing.T) {
+	data := appendHTTP3Frame(nil, http3FrameData, []byte("body"))
+	trailer := func(fields ...qpack.HeaderField) []byte {
+		frame, err := encodeHTTP3Headers(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return frame
+	}
+	large := strings.Repeat("a", http3MaxFieldSectionSize/2)
+
+	tests := []struct {
+		name   string
+		stream []byte
+		err    string
+	}{
+		{"trailer", slices.Concat(data, trailer(qpack.HeaderField{Name: "x-checksum", Value: "1"})), ""},
+		{"oversized trailer", slices.Concat(data, trailer(
+			qpack.HeaderField{Name: "x-a", Value: large},
+			qpack.HeaderField{Name: "x-b", Value: large},
+		)), "exceeds"},
+		{"pseudo header in trailer", slices.Concat(data, trailer(qpack.HeaderField{Name: ":status", Value: "200"})), "pseudo header"},
+		{"second trailer", slices.Concat(data,
+			trailer(qpack.HeaderField{Name: "x-a", Value: "1"}),
+			trailer(qpack.HeaderField{Name: "x-b", Value: "2"}),
+		), "after trailers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{}
+			body := &http3Body{
+				frames: &http3FrameReader{r: quicvarint.NewReader(bytes.NewReader(tt.stream))},
+				resp:   resp,
+			}
+			got, err := io.ReadAll(body)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(got) != "body" || resp.Trailer.Get("X-Checksum") != "1" {
+					t.Errorf("body %q trailer %v", got, resp.Trailer)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/rip-zoyo/orbit-tls/profiles"
+)
+
+type RequestIntent int
+
+const (
+	IntentAuto RequestIntent = iota
+	IntentNavigate
+	IntentFetch
+	IntentImage
+	IntentScript
+	IntentIframe
+	IntentFormSubmit
+)
+
+func (i RequestIntent) String() string {
+	switch i {
+	case IntentNavigate:
+		return "navigate"
+	case IntentFetch:
+		return "fetch"
+	case IntentImage:
+		return "image"
+	case IntentScript:
+		return "script"
+	case IntentIframe:
+		return "iframe"
+	case IntentFormSubmit:
+		return "form-submit"
+	default:
+		return "auto"
+	}
+}
+
+func (i RequestIntent) resolve(method string) RequestIntent {
+	if i != IntentAuto {
+		return i
+	}
+	if method == "GET" || method == "HEAD" {
+		return IntentNavigate
+	}
+	return IntentFetch
+}
+
+func requestIntent(method string, opts *RequestOptions) RequestIntent {
+	if opts == nil {
+		return IntentAuto.resolve(method)
+	}
+	return opts.Intent.resolve(method)
+}
+
+var imageAccept = map[string]string{
+	profiles.FamilyChromium: "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8",
+	profiles.FamilyFirefox:  "image/avif,image/webp,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5",
+	profiles.FamilySafari:   "image/webp,image/avif,image/jxl,image/heic,image/heic-sequence,video/*;q=0.8,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5",
+}
+
+var intentPriority = map[string]map[RequestIntent]string{
+	profiles.FamilyChromium: {
+		IntentNavigate:   "u=0, i",
+		IntentFormSubmit: "u=0, i",
+		IntentIframe:     "u=0, i",
+		IntentFetch:      "u=1, i",
+		IntentScript:     "u=1",
+		IntentImage:      "i",
+	},
+	profiles.FamilyFirefox: {
+		IntentNavigate:   "u=0, i",
+		IntentFormSubmit: "u=0, i",
+		IntentIframe:     "u=4, i",
+		IntentFetch:      "u=4",
+		IntentScript:     "u=2",
+		IntentImage:      "u=5, i",
+	},
+	profiles.FamilySafari: {
+		IntentNavigate:   "u=0, i",
+		IntentFormSubmit: "u=0, i",
+		IntentIframe:     "u=0, i",
+		IntentFetch:      "u=3, i",
+		IntentScript:     "u=2",
+		IntentImage:      "u=5, i",
+	},
+}
+
+func (c *Client) intentHeaders(method string, target *url.URL, opts *RequestOptions) map[string]string {
+	intent := requestIntent(method, opts)
+	var initiator *url.URL
+	userActivated := true
+	if opts != nil {
+		userActivated = !opts.NoUserActivation
+		if opts.Referrer != "" {
+			if parsed, err := url.Parse(opts.Referrer); err == nil && parsed.Host != "" {
+				initiator = parsed
+			}
+		}
+	}
+
+	headers := make(map[string]string)
+
+	switch intent {
+	case IntentNavigate, IntentIframe, IntentFormSubmit:
+		headers["Accept"] = c.profile.Accept
+		headers["Upgrade-Insecure-Requests"] = "1"
+	case IntentImage:
+		headers["Accept"] = imageAccept[c.profile.Family]
+	default:
+		headers["Accept"] = "*/*"
+	}
+	if headers["Accept"] == "" {
+		headers["Accept"] = c.profile.Accept
+	}
+
+	site := fetchSite(initiator, target, intent)
+
+	if potentiallyTrustworthy(target) {
+		headers["Sec-Fetch-Site"] = site
+		switch intent {
+		case IntentNavigate, IntentFormSubmit:
+			headers["Sec-Fetch-Mode"] = "navigate"
+			headers["Sec-Fetch-Dest"] = "document"
+			if userActivated {
+				headers["Sec-Fetch-User"] = "?1"
+			}
+		case IntentIframe:
+			headers["Sec-Fetch-Mode"] = "navigate"
+			headers["Sec-Fetch-Dest"] = "iframe"
+		case IntentImage:
+			headers["Sec-Fetch-Mode"] = "no-cors"
+			headers["Sec-Fetch-Dest"] = "image"
+		case IntentScript:
+			headers["Sec-Fetch-Mode"] = "no-cors"
+			headers["Sec-Fetch-Dest"] = "script"
+		default:
+			headers["Sec-Fetch-Mode"] = "cors"
+			headers["Sec-Fetch-Dest"] = "empty"
+		}
+	}
+
+	if initiator != nil {
+		if referer := referrerValue(initiator, target); referer != "" {
+			headers["Referer"] = referer
+		}
+	}
+
+	safeMethod := method == "GET" || method == "HEAD"
+	switch {
+	case intent == IntentFetch && (!safeMethod || site != "same-origin"),
+		intent == IntentFormSubmit && !safeMethod:
+		if initiator != nil {
+			headers["Origin"] = urlOrigin(initiator)
+		} else {
+			headers["Origin"] = urlOrigin(target)
+		}
+	}
+
+	if priority, ok := intentPriority[c.profile.Family][intent]; ok {
+		headers["Priority"] = priority
+	}
+
+	return headers
+}
+
+func fetchSite(initiator, target *url.URL, intent RequestIntent) string {
+	if initiator == nil {
+		if intent == IntentNavigate {
+			return "none"
+		}
+		return "same-origin"
+	}
+	if sameOrigin(initiator, target) {
+		return "same-origin"
+	}
+	if initiator.Scheme == target.Scheme && registrableDomain(initiator.Hostname()) == registrableDomain(target.Hostname()) {
+		return "same-site"
+	}
+	return "cross-site"
+}
+
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		effectivePort(a) == effectivePort(b)
+}
+
+func effectivePort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+func registrableDomain(host string) string {
+	host = strings.ToLower(host)
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+func urlOrigin(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && port != effectivePort(&url.URL{Scheme: u.Scheme}) {
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u.Scheme + "://" + host
+}
+
+func referrerValue(initiator, target *url.URL) string {
+	if initiator.Scheme == "https" && target.Scheme != "https" {
+		return ""
+	}
+	if !sameOrigin(initiator, target) {
+		return urlOrigin(initiator) + "/"
+	}
+	referrer := *initiator
+	referrer.User = nil
+	referrer.Fragment = ""
+	referrer.RawFragment = ""
+	return referrer.String()
+}
+
+func potentiallyTrustworthy(u *url.URL) bool {
+	if u.Scheme == "https" {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/rip-zoyo/orbit-tls/fingerprint"
+)
+
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type fingerprintKey struct{}
+
+type fingerprintHolder struct {
+	mu   sync.Mutex
+	data *fingerprint.Data
+}
+
+func (h *fingerprintHolder) set(data *fingerprint.Data) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.data = data
+}
+
+func (h *fingerprintHolder) get() *fingerprint.Data {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.data
+}
+
+func withFingerprintHolder(ctx context.Context) (context.Context, *fingerprintHolder) {
+	if holder, ok := ctx.Value(fingerprintKey{}).(*fingerprintHolder); ok {
+		return ctx, holder
+	}
+	holder := &fingerprintHolder{}
+	return context.WithValue(ctx, fingerprintKey{}, holder), holder
+}
+
+func FingerprintFromContext(ctx context.Context) *fingerprint.Data {
+	if holder, ok := ctx.Value(fingerprintKey{}).(*fingerprintHolder); ok {
+		return holder.get()
+	}
+	return nil
+}
+
+type middlewareTransport struct {
+	mu         sync.RWMutex
+	base       http.RoundTripper
+	tracked    http.RoundTripper
+	middleware []Middleware
+	chain      http.RoundTripper
+}
+
+func (c *Client) newMiddlewareTransport(base, fresh http.RoundTripper) *middlewareTransport {
+	tracked := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		transport := base
+		if force, _ := req.Context().Value(forceNewConnectionKey{}).(bool); force {
+			transport = fresh
+		}
+
+		req, use := c.traceConnection(req)
+		resp, err := transport.RoundTrip(req)
+		if details := use.details(); details != nil || err == nil {
+			fp := c.updateFingerprint(details, req)
+			if holder, ok := req.Context().Value(fingerprintKey{}).(*fingerprintHolder); ok {
+				holder.set(fp)
+			}
+		}
+		if err != nil {
+			use.done()
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusSwitchingProtocols || resp.Body == nil {
+			use.done()
+		} else {
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: use.done}
+		}
+		return resp, nil
+	})
+
+	return &middlewareTransport{
+		base:    base,
+		tracked: tracked,
+		chain:   tracked,
+	}
+}
+
+func (t *middlewareTransport) use(middleware ...Middleware) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.middleware = append(t.middleware, middleware...)
+	chain := t.tracked
+	for i := len(t.middleware) - 1; i >= 0; i-- {
+		chain = t.middleware[i](chain)
+	}
+	t.chain = chain
+}
+
+func (t *middlewareTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, _ := withFingerprintHolder(req.Context())
+	if ctx != req.Context() {
+		req = req.WithContext(ctx)
+	}
+
+	t.mu.RLock()
+	chain := t.chain
+	t.mu.RUnlock()
+	return chain.RoundTrip(req)
+}
+
+func (t *middlewareTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+func (c *Client) Use(middleware ...Middleware) {
+	c.middleware.use(middleware...)
+}
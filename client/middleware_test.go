@@ -0,0 +1,51 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/rip-zoyo/orbit-tls/tracking"
+)
+
+func TestFingerprintFromServingConnection(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target, _ := url.Parse(server.URL)
+
+	chrome, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	firefox, err := NewWithOptions("Firefox131", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := chrome.Get(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	firefoxResp, err := firefox.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chromeResp, err := chrome.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	details, ok := chrome.Tracker().GetConnection(target.Host)
+	if !ok {
+		t.Fatal("Chrome client did not record its own connection")
+	}
+	want := tracking.GenerateFingerprintData(chrome.profile, details).JA3
+	if chromeResp.Fingerprint.JA3 != want {
+		t.Errorf("Chrome response JA3 = %s, want its connection's %s", chromeResp.Fingerprint.JA3, want)
+	}
+	if chromeResp.Fingerprint.JA3 == firefoxResp.Fingerprint.JA3 {
+		t.Error("Chrome response reported the Firefox client's fingerprint")
+	}
+
+	if _, ok := tracking.GlobalTracker.GetConnection(target.Host); ok {
+		t.Error("client connections were recorded in the shared GlobalTracker")
+	}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+)
+
+type ProtocolMode int
+
+const (
+	ProtocolAuto ProtocolMode = iota
+	ProtocolHTTP1
+	ProtocolHTTP2
+	ProtocolH2C
+)
+
+func (m ProtocolMode) String() string {
+	switch m {
+	case ProtocolHTTP1:
+		return "http1"
+	case ProtocolHTTP2:
+		return "http2"
+	case ProtocolH2C:
+		return "h2c"
+	default:
+		return "auto"
+	}
+}
+
+func (m ProtocolMode) alpn(profileALPN []string) []string {
+	switch m {
+	case ProtocolHTTP1:
+		return []string{"http/1.1"}
+	case ProtocolHTTP2, ProtocolH2C:
+		return []string{"h2"}
+	default:
+		return profileALPN
+	}
+}
+
+func (m ProtocolMode) transportProtocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	switch m {
+	case ProtocolHTTP1:
+		protocols.SetHTTP1(true)
+	case ProtocolHTTP2:
+		protocols.SetHTTP2(true)
+	case ProtocolH2C:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+	return protocols
+}
+
+func (c *Client) expectedProtocol(u *url.URL) string {
+	switch c.options.Protocol {
+	case ProtocolHTTP1:
+		return "h1"
+	case ProtocolHTTP2:
+		return "h2"
+	case ProtocolH2C:
+		return "h2"
+	}
+
+	if u.Scheme == "https" {
+		for _, proto := range c.tlsConfig.NextProtos {
+			if proto == "h2" {
+				return "h2"
+			}
+		}
+	}
+	return "h1"
+}
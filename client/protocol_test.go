@@ -0,0 +1,188 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProtocolModes(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		protocol ProtocolMode
+		proto    string
+		alpn     string
+	}{
+		{ProtocolAuto, "HTTP/2.0", "h2"},
+		{ProtocolHTTP1, "HTTP/1.1", "h1"},
+		{ProtocolHTTP2, "HTTP/2.0", "h2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.protocol.String(), func(t *testing.T) {
+			c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots, Protocol: tt.protocol})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Proto != tt.proto {
+				t.Errorf("protocol = %s, want %s", resp.Proto, tt.proto)
+			}
+			if resp.TLS == nil {
+				t.Fatal("resp.TLS is nil")
+			}
+			if tt.alpn == "h2" && resp.TLS.NegotiatedProtocol != "h2" {
+				t.Errorf("negotiated %q, want h2", resp.TLS.NegotiatedProtocol)
+			}
+
+			ja4 := c.GetJA4()
+			if len(ja4) < 5 || ja4[3:5] != tt.alpn {
+				t.Errorf("JA4 %q does not carry ALPN %s", ja4, tt.alpn)
+			}
+			if resp.GetJA4() != ja4 {
+				t.Errorf("response JA4 %q, client JA4 %q", resp.GetJA4(), ja4)
+			}
+		})
+	}
+}
+
+func TestHTTP1WireHeaderOrder(t *testing.T) {
+	template, roots := newTLSServer(t, http.NotFoundHandler())
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: template.TLS.Certificates,
+		NextProtos:   []string{"http/1.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	heads := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var names []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if line == "" {
+				break
+			}
+			if name, _, ok := strings.Cut(line, ":"); ok {
+				names = append(names, strings.ToLower(name))
+			}
+		}
+		heads <- names
+		conn.Write([]byte("HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n"))
+	}()
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots, Protocol: ProtocolHTTP1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get("https://"+listener.Addr().String(), map[string]string{"Accept": "text/html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TLS == nil {
+		t.Error("resp.TLS is nil")
+	}
+
+	names := <-heads
+	if len(names) == 0 || names[0] != "host" {
+		t.Fatalf("header names %v, want host first", names)
+	}
+	order := c.profile.HeaderOrder
+	last := -1
+	for _, name := range names[1:] {
+		index := slices.Index(order, name)
+		if index < 0 {
+			continue
+		}
+		if index < last {
+			t.Errorf("header %s out of profile order in %v", name, names)
+		}
+		last = index
+	}
+}
+
+type captureConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (c *captureConn) Write(p []byte) (int, error) {
+	return c.written.Write(p)
+}
+
+func TestHeaderOrderConnReordersHeads(t *testing.T) {
+	first := "POST /a HTTP/1.1\r\nUser-Agent: ua\r\nContent-Length: 4\r\nAccept: */*\r\nCookie: a=1\r\nHost: example.com\r\nCookie: b=2\r\nSec-CH-UA: x\r\n\r\nbody"
+	second := "POST /b HTTP/1.1\r\nTransfer-Encoding: chunked\r\nHost: example.com\r\nAccept: */*\r\n\r\n4\r\nbody\r\n0\r\n\r\n"
+	third := "GET /c HTTP/1.1\r\nAccept: */*\r\nHost: example.com\r\n\r\n"
+
+	capture := &captureConn{}
+	conn := newHeaderOrderConn(capture, []string{"sec-ch-ua", "user-agent", "accept", "cookie"})
+	stream := first + second + third
+	for len(stream) > 0 {
+		n := min(len(stream), 7)
+		if _, err := conn.Write([]byte(stream[:n])); err != nil {
+			t.Fatal(err)
+		}
+		stream = stream[n:]
+	}
+
+	want := "POST /a HTTP/1.1\r\nHost: example.com\r\nsec-ch-ua: x\r\nUser-Agent: ua\r\nAccept: */*\r\nCookie: a=1; b=2\r\nContent-Length: 4\r\n\r\nbody" +
+		"POST /b HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\nTransfer-Encoding: chunked\r\n\r\n4\r\nbody\r\n0\r\n\r\n" +
+		"GET /c HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n\r\n"
+	if got := capture.written.String(); got != want {
+		t.Errorf("wire bytes:\n%q\nwant:\n%q", got, want)
+	}
+	if state := conn.ConnectionState(); state.HandshakeComplete {
+		t.Error("plain connection reported a TLS state")
+	}
+}
+
+func TestH2CPriorKnowledge(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{Protocol: ProtocolH2C})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ProtoMajor != 2 || resp.Text != "HTTP/2.0" {
+		t.Errorf("response %s, server saw %s, want HTTP/2.0", resp.Proto, resp.Text)
+	}
+	if resp.TLS != nil {
+		t.Error("cleartext response carries a TLS state")
+	}
+	if resp.Fingerprint == nil || resp.Fingerprint.Protocol != "h2c" {
+		t.Errorf("fingerprint = %+v, want protocol h2c", resp.Fingerprint)
+	}
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	xproxy "golang.org/x/net/proxy"
+
+	"github.com/rip-zoyo/orbit-tls/tracking"
+)
+
+type proxyKey struct{}
+
+func ProxyFromEnvironment(req *http.Request) (*url.URL, error) {
+	return http.ProxyFromEnvironment(req)
+}
+
+func ProxyURL(fixedURL *url.URL) func(*http.Request) (*url.URL, error) {
+	return http.ProxyURL(fixedURL)
+}
+
+func (c *Client) withProxy(ctx context.Context, scheme, addr string) (context.Context, error) {
+	if c.options.Proxy == nil {
+		return ctx, nil
+	}
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    &url.URL{Scheme: scheme, Host: addr},
+		Header: make(http.Header),
+		Host:   addr,
+	}
+	proxy, err := c.options.Proxy(req)
+	if err != nil {
+		return nil, &ProxyError{Err: err}
+	}
+	if proxy == nil {
+		return ctx, nil
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, &ProxyError{Proxy: proxy.Redacted(), Err: fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)}
+	}
+	return context.WithValue(ctx, proxyKey{}, proxy), nil
+}
+
+func proxyAddr(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	port := "80"
+	switch proxy.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(proxy.Hostname(), port)
+}
+
+func (c *Client) dialProxy(ctx context.Context, network string, proxy *url.URL, addr string) (net.Conn, *tracking.Resolution, error) {
+	conn, resolution, err := c.dialDirect(ctx, network, proxyAddr(proxy))
+	if err != nil {
+		return nil, nil, &ProxyError{Proxy: proxy.Redacted(), Err: err}
+	}
+	if proxy.Scheme == "socks5" || proxy.Scheme == "socks5h" {
+		conn, err = socksConnect(ctx, conn, proxy, addr)
+	} else {
+		conn, err = c.httpConnect(ctx, conn, proxy, addr)
+	}
+	if err != nil {
+		return nil, nil, &ProxyError{Proxy: proxy.Redacted(), Err: err}
+	}
+	return conn, resolution, nil
+}
+
+func (c *Client) httpConnect(ctx context.Context, conn net.Conn, proxy *url.URL, addr string) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         proxy.Hostname(),
+			RootCAs:            c.options.RootCAs,
+			InsecureSkipVerify: c.options.InsecureSkipVerify,
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{"User-Agent": {c.profile.UserAgent}},
+	}
+	if user := proxy.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("CONNECT %s: %s", addr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+type tunnelDialer struct {
+	conn net.Conn
+}
+
+func (d tunnelDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.conn, nil
+}
+
+func socksConnect(ctx context.Context, conn net.Conn, proxy *url.URL, addr string) (net.Conn, error) {
+	var auth *xproxy.Auth
+	if user := proxy.User; user != nil {
+		password, _ := user.Password()
+		auth = &xproxy.Auth{User: user.Username(), Password: password}
+	}
+	dialer, err := xproxy.SOCKS5("tcp", proxyAddr(proxy), auth, tunnelDialer{conn: conn})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tunnel, err := dialer.(xproxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tunnel, nil
+}
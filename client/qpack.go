@@ -0,0 +1,372 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/quic-go/qpack"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+const (
+	http3FrameData    = 0x0
+	http3FrameHeaders = 0x1
+
+	max1xxResponses = 5
+
+	http3MaxFieldSectionSize = http.DefaultMaxHeaderBytes
+)
+
+var defaultPseudoHeaderOrder = []string{":method", ":authority", ":scheme", ":path"}
+
+var http3ConnectionHeaders = map[string]bool{
+	"connection":        true,
+	"host":              true,
+	"keep-alive":        true,
+	"proxy-connection":  true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+}
+
+func (c *Client) http3Fields(req *http.Request) []qpack.HeaderField {
+	authority := req.Host
+	if authority == "" {
+		authority = req.URL.Host
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	pseudo := map[string]string{
+		":method":    method,
+		":authority": authority,
+		":scheme":    req.URL.Scheme,
+		":path":      req.URL.RequestURI(),
+	}
+
+	order := c.profile.PseudoHeaderOrder
+	if len(order) == 0 {
+		order = defaultPseudoHeaderOrder
+	}
+	fields := make([]qpack.HeaderField, 0, len(pseudo)+len(req.Header)+1)
+	for _, name := range slices.Concat(order, defaultPseudoHeaderOrder) {
+		if value, ok := pseudo[name]; ok {
+			fields = append(fields, qpack.HeaderField{Name: name, Value: value})
+			delete(pseudo, name)
+		}
+	}
+
+	rank := make(map[string]int, len(c.profile.HeaderOrder))
+	for i, name := range c.profile.HeaderOrder {
+		if !strings.HasPrefix(name, ":") {
+			rank[strings.ToLower(name)] = i
+		}
+	}
+
+	names := make([]string, 0, len(req.Header)+1)
+	values := make(map[string][]string, len(req.Header)+1)
+	for name, vals := range req.Header {
+		lower := strings.ToLower(name)
+		if http3ConnectionHeaders[lower] || (lower == "te" && !strings.EqualFold(strings.Join(vals, ","), "trailers")) {
+			continue
+		}
+		if _, seen := values[lower]; !seen {
+			names = append(names, lower)
+		}
+		values[lower] = append(values[lower], vals...)
+	}
+	if _, ok := values["content-length"]; !ok && req.ContentLength > 0 {
+		names = append(names, "content-length")
+		values["content-length"] = []string{strconv.FormatInt(req.ContentLength, 10)}
+	}
+
+	sort.Strings(names)
+	sort.SliceStable(names, func(i, j int) bool {
+		return headerRank(rank, names[i]) < headerRank(rank, names[j])
+	})
+	for _, name := range names {
+		for _, value := range values[name] {
+			fields = append(fields, qpack.HeaderField{Name: name, Value: value})
+		}
+	}
+	return fields
+}
+
+func headerRank(rank map[string]int, name string) int {
+	if index, ok := rank[name]; ok {
+		return index
+	}
+	return math.MaxInt
+}
+
+func appendHTTP3Frame(b []byte, frameType uint64, payload []byte) []byte {
+	b = quicvarint.Append(b, frameType)
+	b = quicvarint.Append(b, uint64(len(payload)))
+	return append(b, payload...)
+}
+
+func encodeHTTP3Headers(fields []qpack.HeaderField) ([]byte, error) {
+	var block bytes.Buffer
+	encoder := qpack.NewEncoder(&block)
+	for _, field := range fields {
+		if err := encoder.WriteField(field); err != nil {
+			return nil, err
+		}
+	}
+	return appendHTTP3Frame(nil, http3FrameHeaders, block.Bytes()), nil
+}
+
+func writeHTTP3Request(str *quic.Stream, req *http.Request, fields []qpack.HeaderField) error {
+	frame, err := encodeHTTP3Headers(fields)
+	if err != nil {
+		return err
+	}
+	if _, err := str.Write(frame); err != nil {
+		return err
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return str.Close()
+	}
+
+	defer req.Body.Close()
+	buf := make([]byte, 16*1024)
+	for {
+		n, err := req.Body.Read(buf)
+		if n > 0 {
+			if _, werr := str.Write(appendHTTP3Frame(nil, http3FrameData, buf[:n])); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return str.Close()
+		}
+		if err != nil {
+			str.CancelWrite(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+			return err
+		}
+	}
+}
+
+type http3FrameReader struct {
+	r     quicvarint.Reader
+	trace *httptrace.ClientTrace
+	first bool
+}
+
+func (f *http3FrameReader) next() (uint64, uint64, error) {
+	frameType, err := quicvarint.Read(f.r)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !f.first {
+		f.first = true
+		if f.trace != nil && f.trace.GotFirstResponseByte != nil {
+			f.trace.GotFirstResponseByte()
+		}
+	}
+	length, err := quicvarint.Read(f.r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return frameType, length, err
+}
+
+func (f *http3FrameReader) headers(length uint64) (http.Header, map[string]string, error) {
+	if length > http3MaxFieldSectionSize {
+		return nil, nil, fmt.Errorf("http3: HEADERS frame too large: %d bytes", length)
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(f.r, block); err != nil {
+		return nil, nil, err
+	}
+
+	header := make(http.Header)
+	pseudo := make(map[string]string)
+	size := 0
+	decode := qpack.NewDecoder().Decode(block)
+	for {
+		field, err := decode()
+		if err == io.EOF {
+			return header, pseudo, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("http3: invalid header block: %w", err)
+		}
+		size += len(field.Name) + len(field.Value) + 32
+		if size > http3MaxFieldSectionSize {
+			return nil, nil, fmt.Errorf("http3: header section exceeds %d bytes", http3MaxFieldSectionSize)
+		}
+		if field.IsPseudo() {
+			if len(header) > 0 {
+				return nil, nil, fmt.Errorf("http3: pseudo header %q after regular headers", field.Name)
+			}
+			pseudo[field.Name] = field.Value
+			continue
+		}
+		header.Add(field.Name, field.Value)
+	}
+}
+
+func parseHTTP3Status(pseudo map[string]string) (int, error) {
+	for name := range pseudo {
+		if name != ":status" {
+			return 0, fmt.Errorf("http3: invalid response pseudo header %q", name)
+		}
+	}
+	value := pseudo[":status"]
+	status, err := strconv.Atoi(value)
+	if err != nil || len(value) != 3 || status < 100 || status > 599 || status == http.StatusSwitchingProtocols {
+		return 0, fmt.Errorf("http3: invalid status %q", value)
+	}
+	return status, nil
+}
+
+func readHTTP3Response(str *quic.Stream, req *http.Request) (*http.Response, error) {
+	trace := httptrace.ContextClientTrace(req.Context())
+	frames := &http3FrameReader{r: quicvarint.NewReader(str), trace: trace}
+
+	informational := 0
+	for {
+		frameType, length, err := frames.next()
+		if err != nil {
+			return nil, err
+		}
+		if frameType == http3FrameData {
+			return nil, errors.New("http3: expected first frame to be a HEADERS frame")
+		}
+		if frameType != http3FrameHeaders {
+			if _, err := io.CopyN(io.Discard, frames.r, int64(length)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		header, pseudo, err := frames.headers(length)
+		if err != nil {
+			return nil, err
+		}
+		status, err := parseHTTP3Status(pseudo)
+		if err != nil {
+			return nil, err
+		}
+		if status < 200 {
+			informational++
+			if informational > max1xxResponses {
+				return nil, errors.New("http3: too many 1xx informational responses")
+			}
+			if trace != nil && trace.Got1xxResponse != nil {
+				if err := trace.Got1xxResponse(status, textproto.MIMEHeader(header)); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		resp := &http.Response{
+			Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+			StatusCode:    status,
+			Proto:         "HTTP/3.0",
+			ProtoMajor:    3,
+			Header:        header,
+			ContentLength: -1,
+			Request:       req,
+		}
+		if value := header.Get("Content-Length"); value != "" {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				resp.ContentLength = n
+			}
+		}
+		if status == http.StatusNoContent || status == http.StatusNotModified {
+			resp.ContentLength = 0
+		}
+		resp.Body = &http3Body{str: str, frames: frames, resp: resp}
+		return resp, nil
+	}
+}
+
+type http3Body struct {
+	str       *quic.Stream
+	frames    *http3FrameReader
+	resp      *http.Response
+	remaining uint64
+	trailers  bool
+	closeOnce sync.Once
+	onClose   func()
+}
+
+func (b *http3Body) Read(p []byte) (int, error) {
+	for b.remaining == 0 {
+		frameType, length, err := b.frames.next()
+		if err != nil {
+			return 0, http3Error(err)
+		}
+		switch frameType {
+		case http3FrameData:
+			if b.trailers {
+				return 0, errors.New("http3: DATA frame received after trailers")
+			}
+			b.remaining = length
+		case http3FrameHeaders:
+			if b.trailers {
+				return 0, errors.New("http3: additional HEADERS frame received after trailers")
+			}
+			trailer, pseudo, err := b.frames.headers(length)
+			if err != nil {
+				return 0, err
+			}
+			if len(pseudo) > 0 {
+				return 0, errors.New("http3: pseudo header in trailers")
+			}
+			b.trailers = true
+			b.resp.Trailer = trailer
+		default:
+			if _, err := io.CopyN(io.Discard, b.frames.r, int64(length)); err != nil {
+				return 0, http3Error(err)
+			}
+		}
+	}
+
+	if uint64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.frames.r.Read(p)
+	b.remaining -= uint64(n)
+	if err == io.EOF && b.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return n, http3Error(err)
+}
+
+func (b *http3Body) Close() error {
+	b.closeOnce.Do(func() {
+		b.str.CancelRead(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		if b.onClose != nil {
+			b.onClose()
+		}
+	})
+	return nil
+}
+
+func http3Error(err error) error {
+	var streamErr *quic.StreamError
+	if errors.As(err, &streamErr) {
+		return &http3.Error{Remote: streamErr.Remote, ErrorCode: http3.ErrCode(streamErr.ErrorCode)}
+	}
+	return err
+}
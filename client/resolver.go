@@ -0,0 +1,377 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/rip-zoyo/orbit-tls/tracking"
+)
+
+type IPPreference int
+
+const (
+	IPDefault IPPreference = iota
+	PreferIPv4
+	PreferIPv6
+	IPv4Only
+	IPv6Only
+)
+
+const (
+	happyEyeballsDelay = 300 * time.Millisecond
+	minDNSCacheTTL     = 30 * time.Second
+)
+
+type resolver struct {
+	hosts      map[string]string
+	server     *url.URL
+	preference IPPreference
+	exchange   func(ctx context.Context, query []byte) ([]byte, error)
+
+	mu    sync.Mutex
+	cache map[string]cachedLookup
+}
+
+type cachedLookup struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+func (c *Client) newResolver(opts ClientOptions) (*resolver, error) {
+	r := &resolver{
+		hosts:      make(map[string]string, len(opts.Hosts)),
+		preference: opts.IPPreference,
+		cache:      make(map[string]cachedLookup),
+	}
+	for host, ip := range opts.Hosts {
+		r.hosts[strings.ToLower(host)] = ip
+	}
+
+	if opts.DNSResolver == "" {
+		return r, nil
+	}
+
+	server, err := url.Parse(opts.DNSResolver)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DNS resolver: %w", err)
+	}
+	switch server.Scheme {
+	case "https":
+		r.exchange = func(ctx context.Context, query []byte) ([]byte, error) {
+			return dohExchange(ctx, c.httpClient, server.String(), query)
+		}
+	case "tls":
+		port := server.Port()
+		if port == "" {
+			port = "853"
+		}
+		addr := net.JoinHostPort(server.Hostname(), port)
+		if value, ok := r.static(server.Hostname(), port); ok {
+			ip, _, _ := strings.Cut(value, ",")
+			addr = net.JoinHostPort(strings.TrimSpace(ip), port)
+		}
+		r.exchange = func(ctx context.Context, query []byte) ([]byte, error) {
+			return dotExchange(ctx, addr, server.Hostname(), opts.RootCAs, query)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DNS resolver scheme: %s", server.Scheme)
+	}
+	r.server = server
+	return r, nil
+}
+
+func (r *resolver) custom() bool {
+	return len(r.hosts) > 0 || r.server != nil || r.preference != IPDefault
+}
+
+func (r *resolver) lookup(ctx context.Context, host, port string) ([]netip.Addr, string, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, "literal", nil
+	}
+
+	if value, ok := r.static(host, port); ok {
+		var addrs []netip.Addr
+		for _, field := range strings.Split(value, ",") {
+			addr, err := netip.ParseAddr(strings.TrimSpace(field))
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid address for %s: %w", host, err)
+			}
+			addrs = append(addrs, addr)
+		}
+		return r.order(addrs), "static", nil
+	}
+
+	if r.server == nil || strings.EqualFold(r.server.Hostname(), host) {
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, "", err
+		}
+		return r.order(addrs), "system", nil
+	}
+
+	addrs, err := r.query(ctx, host)
+	if err != nil {
+		return nil, "", err
+	}
+	source := "doh"
+	if r.server.Scheme == "tls" {
+		source = "dot"
+	}
+	return r.order(addrs), source, nil
+}
+
+func (r *resolver) static(host, port string) (string, bool) {
+	host = strings.ToLower(host)
+	if value, ok := r.hosts[net.JoinHostPort(host, port)]; ok {
+		return value, true
+	}
+	value, ok := r.hosts[host]
+	return value, ok
+}
+
+func (r *resolver) query(ctx context.Context, host string) ([]netip.Addr, error) {
+	key := strings.ToLower(host)
+	r.mu.Lock()
+	cached, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.addrs, nil
+	}
+
+	var types []dnsmessage.Type
+	if r.preference != IPv4Only {
+		types = append(types, dnsmessage.TypeAAAA)
+	}
+	if r.preference != IPv6Only {
+		types = append(types, dnsmessage.TypeA)
+	}
+
+	type answer struct {
+		addrs []netip.Addr
+		ttl   uint32
+		err   error
+	}
+	answers := make([]answer, len(types))
+	var wg sync.WaitGroup
+	for i, qtype := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			query, err := buildDNSQuery(host, qtype)
+			if err != nil {
+				answers[i].err = err
+				return
+			}
+			response, err := r.exchange(ctx, query)
+			if err != nil {
+				answers[i].err = err
+				return
+			}
+			answers[i].addrs, answers[i].ttl, answers[i].err = parseAddressAnswer(response)
+		}()
+	}
+	wg.Wait()
+
+	var addrs []netip.Addr
+	var errs []error
+	ttl := uint32(0)
+	for _, a := range answers {
+		if a.err != nil {
+			errs = append(errs, a.err)
+			continue
+		}
+		addrs = append(addrs, a.addrs...)
+		if a.ttl > 0 && (ttl == 0 || a.ttl < ttl) {
+			ttl = a.ttl
+		}
+	}
+	if len(addrs) == 0 {
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, errors.Join(errs...))
+		}
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	r.mu.Lock()
+	r.cache[key] = cachedLookup{
+		addrs:   addrs,
+		expires: time.Now().Add(max(time.Duration(ttl)*time.Second, minDNSCacheTTL)),
+	}
+	r.mu.Unlock()
+	return addrs, nil
+}
+
+func systemResolution(addr string, conn net.Conn) *tracking.Resolution {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	resolution := &tracking.Resolution{Host: host, Source: "system"}
+	if _, err := netip.ParseAddr(host); err == nil {
+		resolution.Source = "literal"
+	}
+	if remote, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		resolution.Addrs = []string{remote.IP.String()}
+	}
+	return resolution
+}
+
+func (r *resolver) order(addrs []netip.Addr) []netip.Addr {
+	ordered := make([]netip.Addr, 0, len(addrs))
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		switch {
+		case r.preference == IPv4Only && !addr.Is4():
+		case r.preference == IPv6Only && !addr.Is6():
+		default:
+			ordered = append(ordered, addr)
+		}
+	}
+
+	switch r.preference {
+	case PreferIPv4:
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Is4() && !ordered[j].Is4() })
+	case PreferIPv6:
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Is6() && !ordered[j].Is6() })
+	}
+	return ordered
+}
+
+func (c *Client) dialResolved(ctx context.Context, network, addr string) (net.Conn, *tracking.Resolution, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addrs, resolution, err := c.resolve(ctx, host, port)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := c.dialAddrs(ctx, network, addrs, port)
+	return conn, resolution, err
+}
+
+func (c *Client) resolve(ctx context.Context, host, port string) ([]netip.Addr, *tracking.Resolution, error) {
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	addrs, source, err := c.resolver.lookup(ctx, host, port)
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("no usable addresses for %s", host)
+	}
+	if trace != nil && trace.DNSDone != nil {
+		ipAddrs := make([]net.IPAddr, len(addrs))
+		for i, ip := range addrs {
+			ipAddrs[i] = net.IPAddr{IP: ip.AsSlice()}
+		}
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: ipAddrs, Err: err})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolution := &tracking.Resolution{Host: host, Source: source}
+	for _, ip := range addrs {
+		resolution.Addrs = append(resolution.Addrs, ip.String())
+	}
+	return addrs, resolution, nil
+}
+
+func (c *Client) dialAddrs(ctx context.Context, network string, addrs []netip.Addr, port string) (net.Conn, error) {
+	var primaries, fallbacks []netip.Addr
+	for _, addr := range addrs {
+		if addr.Is4() == addrs[0].Is4() {
+			primaries = append(primaries, addr)
+		} else {
+			fallbacks = append(fallbacks, addr)
+		}
+	}
+	if len(fallbacks) == 0 {
+		return c.dialSerial(ctx, network, primaries, port)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, 2)
+	start := func(addrs []netip.Addr) {
+		go func() {
+			conn, err := c.dialSerial(ctx, network, addrs, port)
+			results <- result{conn, err}
+		}()
+	}
+
+	start(primaries)
+	pending := 1
+	fallbackStarted := false
+	timer := time.NewTimer(happyEyeballsDelay)
+	defer timer.Stop()
+
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				start(fallbacks)
+				pending++
+				fallbackStarted = true
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if pending > 0 {
+					go func() {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}()
+				}
+				return res.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if !fallbackStarted {
+				start(fallbacks)
+				pending++
+				fallbackStarted = true
+				continue
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+func (c *Client) dialSerial(ctx context.Context, network string, addrs []netip.Addr, port string) (net.Conn, error) {
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := c.netDialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,366 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type dnsServer struct {
+	mu      sync.Mutex
+	queries []dnsmessage.Type
+	records map[dnsmessage.Type][]netip.Addr
+}
+
+func newDNSServer() *dnsServer {
+	return &dnsServer{records: map[dnsmessage.Type][]netip.Addr{
+		dnsmessage.TypeA:    {netip.MustParseAddr("127.0.0.1")},
+		dnsmessage.TypeAAAA: {netip.MustParseAddr("::1")},
+	}}
+}
+
+func (s *dnsServer) answer(query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.queries = append(s.queries, question.Type)
+	s.mu.Unlock()
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, RecursionAvailable: true})
+	builder.StartQuestions()
+	builder.Question(question)
+	builder.StartAnswers()
+	resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 300}
+	for _, addr := range s.records[question.Type] {
+		if addr.Is4() {
+			builder.AResource(resource, dnsmessage.AResource{A: addr.As4()})
+		} else {
+			builder.AAAAResource(resource, dnsmessage.AAAAResource{AAAA: addr.As16()})
+		}
+	}
+	return builder.Finish()
+}
+
+func (s *dnsServer) queried() []dnsmessage.Type {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.queries)
+}
+
+func (s *dnsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/dns-query" {
+		io.WriteString(w, "ok")
+		return
+	}
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+		http.Error(w, "not a DoH request", http.StatusBadRequest)
+		return
+	}
+	query, _ := io.ReadAll(r.Body)
+	answer, err := s.answer(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/dns-message")
+	w.Write(answer)
+}
+
+func (s *dnsServer) serveTLS(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				answer, err := s.answer(query)
+				if err != nil {
+					return
+				}
+				conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(answer))))
+				conn.Write(answer)
+			}
+		}()
+	}
+}
+
+func TestDoHResolver(t *testing.T) {
+	dns := newDNSServer()
+	dns.records[dnsmessage.TypeAAAA] = nil
+	server, roots := newTLSServer(t, dns)
+	_, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "https://"), ":")
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{
+		RootCAs:     roots,
+		DNSResolver: server.URL + "/dns-query",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get("https://example.com:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "ok" {
+		t.Errorf("response = %q, want ok", resp.Text)
+	}
+
+	var resolution *ConnectionInfo
+	for _, conn := range c.Connections() {
+		if strings.HasPrefix(conn.Addr, "example.com:") {
+			resolution = &conn
+		}
+	}
+	if resolution == nil || resolution.Details == nil || resolution.Details.Resolution == nil {
+		t.Fatalf("no tracked resolution for example.com in %+v", c.Connections())
+	}
+	if got := resolution.Details.Resolution; got.Source != "doh" || !slices.Equal(got.Addrs, []string{"127.0.0.1"}) {
+		t.Errorf("resolution = %+v, want 127.0.0.1 from doh", got)
+	}
+
+	queried := dns.queried()
+	if _, _, err := c.resolve(context.Background(), "example.com", port); err != nil {
+		t.Fatal(err)
+	}
+	if again := dns.queried(); len(again) != len(queried) {
+		t.Errorf("cached lookup sent %d more queries", len(again)-len(queried))
+	}
+}
+
+func TestDoTResolver(t *testing.T) {
+	template, roots := newTLSServer(t, http.NotFoundHandler())
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: template.TLS.Certificates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	dns := newDNSServer()
+	go dns.serveTLS(listener)
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{
+		RootCAs:     roots,
+		DNSResolver: "tls://" + listener.Addr().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, resolution, err := c.resolve(context.Background(), "example.com", "443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolution.Source != "dot" {
+		t.Errorf("source = %q, want dot", resolution.Source)
+	}
+	want := []netip.Addr{netip.MustParseAddr("::1"), netip.MustParseAddr("127.0.0.1")}
+	if !slices.Equal(addrs, want) {
+		t.Errorf("addrs = %v, want %v", addrs, want)
+	}
+
+	untrusted, err := NewWithOptions("Chrome138", &ClientOptions{DNSResolver: "tls://" + listener.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := untrusted.resolve(context.Background(), "example.com", "443"); err == nil {
+		t.Error("DoT resolver accepted a certificate outside the trusted roots")
+	}
+}
+
+func TestStaticHosts(t *testing.T) {
+	c, err := NewWithOptions("Chrome138", &ClientOptions{
+		Hosts: map[string]string{
+			"Example.com":          "203.0.113.10, 2001:db8::10",
+			"example.com:8443":     "203.0.113.20",
+			"broken.example.com":   "not-an-ip",
+			"api.example.com:8443": "2001:db8::30",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host    string
+		port    string
+		want    []string
+		source  string
+		wantErr bool
+	}{
+		{"example.com", "443", []string{"203.0.113.10", "2001:db8::10"}, "static", false},
+		{"EXAMPLE.COM", "443", []string{"203.0.113.10", "2001:db8::10"}, "static", false},
+		{"example.com", "8443", []string{"203.0.113.20"}, "static", false},
+		{"api.example.com", "8443", []string{"2001:db8::30"}, "static", false},
+		{"198.51.100.7", "443", []string{"198.51.100.7"}, "literal", false},
+		{"broken.example.com", "443", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+":"+tt.port, func(t *testing.T) {
+			addrs, source, err := c.resolver.lookup(context.Background(), tt.host, tt.port)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("lookup succeeded with %v", addrs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, addr := range addrs {
+				got = append(got, addr.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("addrs = %q, want %q", got, tt.want)
+			}
+			if source != tt.source {
+				t.Errorf("source = %q, want %q", source, tt.source)
+			}
+		})
+	}
+}
+
+func TestIPPreference(t *testing.T) {
+	tests := []struct {
+		name       string
+		preference IPPreference
+		want       []string
+		queries    []dnsmessage.Type
+	}{
+		{"default", IPDefault, []string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2"}, []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}},
+		{"prefer ipv4", PreferIPv4, []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "2001:db8::2"}, []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}},
+		{"prefer ipv6", PreferIPv6, []string{"2001:db8::1", "2001:db8::2", "192.0.2.1", "192.0.2.2"}, []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}},
+		{"ipv4 only", IPv4Only, []string{"192.0.2.1", "192.0.2.2"}, []dnsmessage.Type{dnsmessage.TypeA}},
+		{"ipv6 only", IPv6Only, []string{"2001:db8::1", "2001:db8::2"}, []dnsmessage.Type{dnsmessage.TypeAAAA}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewWithOptions("Chrome138", &ClientOptions{
+				IPPreference: tt.preference,
+				Hosts:        map[string]string{"example.com": "2001:db8::1, 192.0.2.1, 2001:db8::2, ::ffff:192.0.2.2"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			addrs, _, err := c.resolver.lookup(context.Background(), "example.com", "443")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, addr := range addrs {
+				got = append(got, addr.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %q, want %q", got, tt.want)
+			}
+
+			dns := newDNSServer()
+			server, roots := newTLSServer(t, dns)
+			c, err = NewWithOptions("Chrome138", &ClientOptions{
+				RootCAs:      roots,
+				IPPreference: tt.preference,
+				DNSResolver:  server.URL + "/dns-query",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := c.resolve(context.Background(), "example.com", "443"); err != nil {
+				t.Fatal(err)
+			}
+			queried := dns.queried()
+			slices.Sort(queried)
+			if !slices.Equal(queried, tt.queries) {
+				t.Errorf("queried %v, want %v", queried, tt.queries)
+			}
+		})
+	}
+}
+
+func TestHappyEyeballsFallback(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	addrs := []netip.Addr{netip.MustParseAddr("::1"), netip.MustParseAddr("127.0.0.1")}
+
+	tests := []struct {
+		name     string
+		hang     bool
+		minDelay time.Duration
+		maxDelay time.Duration
+	}{
+		{"refused primary falls back at once", false, 0, happyEyeballsDelay},
+		{"stalled primary falls back after the delay", true, happyEyeballsDelay, 10 * happyEyeballsDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("Chrome138")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.hang {
+				c.netDialer.ControlContext = func(ctx context.Context, network, address string, _ syscall.RawConn) error {
+					if strings.HasPrefix(address, "[::1]") {
+						<-ctx.Done()
+						return ctx.Err()
+					}
+					return nil
+				}
+			}
+
+			start := time.Now()
+			conn, err := c.dialAddrs(context.Background(), "tcp", addrs, port)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if remote := conn.RemoteAddr().(*net.TCPAddr); !remote.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+				t.Errorf("connected to %v, want the IPv4 fallback", remote)
+			}
+			if elapsed < tt.minDelay || elapsed >= tt.maxDelay {
+				t.Errorf("fallback took %v, want between %v and %v", elapsed, tt.minDelay, tt.maxDelay)
+			}
+		})
+	}
+}
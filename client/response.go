@@ -0,0 +1,44 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+func (r *Response) Bytes() []byte {
+	return r.body
+}
+
+func (r *Response) JSON(v interface{}) error {
+	if err := json.Unmarshal(r.body, v); err != nil {
+		return fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	return nil
+}
+
+func (r *Response) Raise() error {
+	if r.StatusCode >= 200 && r.StatusCode < 300 {
+		return nil
+	}
+	return &StatusError{StatusCode: r.StatusCode, Status: r.Status, URL: r.URL}
+}
+
+func decodeText(contentType string, body []byte) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return string(body)
+	}
+
+	encoding, err := htmlindex.Get(params["charset"])
+	if err != nil {
+		return string(body)
+	}
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		return string(body)
+	}
+	return string(decoded)
+}
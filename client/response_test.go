@@ -0,0 +1,143 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"no content type", "", "caf\xc3\xa9", "café"},
+		{"no charset", "text/html", "caf\xc3\xa9", "café"},
+		{"utf-8", "text/html; charset=utf-8", "caf\xc3\xa9", "café"},
+		{"latin-1", "text/plain; charset=ISO-8859-1", "caf\xe9", "café"},
+		{"windows-1252", "text/plain; charset=windows-1252", "\x80 5", "€ 5"},
+		{"quoted shift_jis", `text/html; charset="Shift_JIS"`, "\x93\xfa\x96\x7b", "日本"},
+		{"unknown charset", "text/plain; charset=x-unknown", "caf\xe9", "caf\xe9"},
+		{"malformed content type", "text/plain; charset", "caf\xe9", "caf\xe9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeText(tt.contentType, []byte(tt.body)); got != tt.want {
+				t.Errorf("decodeText(%q) = %q, want %q", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseDecoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"name":"orbit","tags":["a","b"]}`)
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/plain; charset=iso-8859-1")
+			io.WriteString(w, "caf\xe9")
+		default:
+			io.WriteString(w, "not json")
+		}
+	}))
+	defer server.Close()
+
+	c, err := New("Chrome138")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Get(server.URL + "/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	if err := resp.JSON(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Name != "orbit" || len(payload.Tags) != 2 {
+		t.Errorf("decoded %+v", payload)
+	}
+
+	resp, err = c.Get(server.URL + "/text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var syntaxErr *json.SyntaxError
+	if err := resp.JSON(&payload); !errors.As(err, &syntaxErr) {
+		t.Errorf("JSON error = %v, want a wrapped *json.SyntaxError", err)
+	}
+
+	resp, err = c.Get(server.URL + "/latin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "café" {
+		t.Errorf("text = %q, want café decoded from ISO-8859-1", resp.Text)
+	}
+	if string(resp.Bytes()) != "caf\xe9" {
+		t.Errorf("bytes = %q, want the raw body", resp.Bytes())
+	}
+}
+
+func TestRaise(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNoContent, false},
+		{http.StatusNotModified, true},
+		{http.StatusNotFound, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusServiceUnavailable, true},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	c, err := New("Chrome138")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			resp, err := c.Request(http.MethodGet, server.URL, nil, &RequestOptions{
+				Params: map[string]string{"status": strconv.Itoa(tt.status)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = resp.Raise()
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Raise() = %v, want nil", err)
+				}
+				return
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("Raise() = %v, want *StatusError", err)
+			}
+			if statusErr.StatusCode != tt.status || statusErr.URL != resp.URL {
+				t.Errorf("status error %+v, want code %d for %s", statusErr, tt.status, resp.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+)
+
+func (c *Client) RoundTripper() http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx, holder := withFingerprintHolder(req.Context())
+		resp, err := c.middleware.RoundTrip(c.prepareRequest(req.WithContext(ctx)))
+		if err != nil {
+			return nil, c.classifyError(err, req.URL, holder.get())
+		}
+
+		if requestIntent(req.Method, nil) == IntentNavigate {
+			c.clientHints.update(urlOrigin(resp.Request.URL), resp.Header)
+		}
+		return resp, nil
+	})
+}
+
+func (c *Client) HTTPClient() *http.Client {
+	return &http.Client{
+		Transport: c.RoundTripper(),
+		Timeout:   c.httpClient.Timeout,
+	}
+}
+
+func (c *Client) prepareRequest(req *http.Request) *http.Request {
+	out := req.Clone(req.Context())
+	if out.Header == nil {
+		out.Header = make(http.Header)
+	}
+
+	for _, h := range c.headers.snapshot() {
+		if req.Header.Get(h.name) == "" {
+			out.Header.Add(h.name, h.value)
+		}
+	}
+
+	profileHeaders := c.getProfileHeaders(req.Method, req.URL, nil)
+	for name, value := range profileHeaders {
+		if out.Header.Get(name) != "" {
+			continue
+		}
+		if c.sendsHeader(name) || strings.EqualFold(name, "Connection") {
+			out.Header.Set(name, value)
+		}
+	}
+	c.crumbleCookies(out.Header)
+
+	return out
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestRoundTripperAppliesProfile(t *testing.T) {
+	var mu sync.Mutex
+	var extensions []uint16
+	headers := make(chan http.Header, 1)
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+	}))
+	server.TLS.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		mu.Lock()
+		extensions = hello.Extensions
+		mu.Unlock()
+		return nil, nil
+	}
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetHeader("X-Client", "orbit")
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.RoundTripper().RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	got := <-headers
+	if got.Get("User-Agent") != c.profile.UserAgent {
+		t.Errorf("User-Agent = %q, want the profile's", got.Get("User-Agent"))
+	}
+	if got.Get("Accept") != "application/json" {
+		t.Errorf("Accept = %q, want the caller's", got.Get("Accept"))
+	}
+	if got.Get("X-Client") != "orbit" {
+		t.Errorf("X-Client = %q, want the client header", got.Get("X-Client"))
+	}
+	if got.Get("Sec-Ch-Ua") == "" || got.Get("Sec-Fetch-Mode") != "navigate" {
+		t.Errorf("profile headers missing: %v", got)
+	}
+	if len(req.Header) != 1 {
+		t.Errorf("caller's request was modified: %v", req.Header)
+	}
+
+	if resp.ProtoMajor != 2 || resp.TLS == nil || resp.TLS.CurveID != tls.X25519MLKEM768 {
+		t.Errorf("response %s over %+v, want HTTP/2 with X25519MLKEM768", resp.Proto, resp.TLS)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Contains(extensions, extensionECH) {
+		t.Errorf("ClientHello extensions %v do not carry Chrome's GREASE ECH", extensions)
+	}
+	if c.GetJA4() == "" {
+		t.Error("no fingerprint recorded for the round trip")
+	}
+}
+
+func TestHTTPClientRedirectsAndCookies(t *testing.T) {
+	var mu sync.Mutex
+	agents := make(map[string]string)
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.URL.Path] = r.Header.Get("User-Agent")
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/start":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			http.Redirect(w, r, "/landing", http.StatusFound)
+		case "/landing":
+			cookie, _ := r.Cookie("session")
+			if cookie != nil {
+				io.WriteString(w, cookie.Value)
+			}
+		}
+	}))
+
+	c, err := NewWithOptions("Firefox131", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := c.HTTPClient()
+	httpClient.Jar, _ = cookiejar.New(nil)
+
+	resp, err := httpClient.Get(server.URL + "/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.Request.URL.Path != "/landing" {
+		t.Errorf("final path %s, want /landing", resp.Request.URL.Path)
+	}
+	if string(body) != "abc" {
+		t.Errorf("landing page saw session cookie %q, want abc", body)
+	}
+
+	mu.Lock()
+	for _, path := range []string{"/start", "/landing"} {
+		if agents[path] != c.profile.UserAgent {
+			t.Errorf("%s saw User-Agent %q, want the profile's", path, agents[path])
+		}
+	}
+	mu.Unlock()
+
+	resp, err = httpClient.Get(server.URL + "/landing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "abc" {
+		t.Errorf("jar did not replay the session cookie, got %q", body)
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+type Timings struct {
+	DNS              time.Duration `json:"dns"`
+	Connect          time.Duration `json:"connect"`
+	TLSHandshake     time.Duration `json:"tls_handshake"`
+	TimeToFirstByte  time.Duration `json:"time_to_first_byte"`
+	TLSHandshakes    int           `json:"tls_handshakes"`
+	ConnectionReused bool          `json:"connection_reused"`
+}
+
+type timingKey struct{}
+
+type timingRecorder struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart map[string]time.Time
+	connectDone  time.Time
+	timings      Timings
+}
+
+func withTimingRecorder(ctx context.Context, start time.Time) (context.Context, *timingRecorder) {
+	recorder := &timingRecorder{
+		start:        start,
+		connectStart: make(map[string]time.Time),
+	}
+	ctx = context.WithValue(ctx, timingKey{}, recorder)
+	return httptrace.WithClientTrace(ctx, recorder.trace()), recorder
+}
+
+func timingRecorderFrom(ctx context.Context) *timingRecorder {
+	recorder, _ := ctx.Value(timingKey{}).(*timingRecorder)
+	return recorder
+}
+
+func (r *timingRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if !r.dnsStart.IsZero() {
+				r.timings.DNS += time.Since(r.dnsStart)
+				r.dnsStart = time.Time{}
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.connectStart[network+" "+addr] = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			key := network + " " + addr
+			if started, ok := r.connectStart[key]; ok && err == nil {
+				r.connectDone = time.Now()
+				r.timings.Connect += r.connectDone.Sub(started)
+			}
+			delete(r.connectStart, key)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.timings.ConnectionReused = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.timings.TimeToFirstByte = time.Since(r.start)
+		},
+	}
+}
+
+func (r *timingRecorder) handshakeDone() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.connectDone.IsZero() {
+		return
+	}
+	r.timings.TLSHandshake += time.Since(r.connectDone)
+	r.timings.TLSHandshakes++
+	r.connectDone = time.Time{}
+}
+
+func (r *timingRecorder) result() Timings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.timings
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	_, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "https://"), ":")
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{
+		RootCAs: roots,
+		Hosts:   map[string]string{"example.com": "127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "https://example.com:" + port
+
+	resp, err := c.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := resp.Timings
+	if first.ConnectionReused {
+		t.Error("first request reported a reused connection")
+	}
+	if first.DNS <= 0 || first.Connect <= 0 || first.TLSHandshake <= 0 {
+		t.Errorf("fresh connection timings %+v, want DNS, connect and TLS recorded", first)
+	}
+	if first.TLSHandshakes != 1 {
+		t.Errorf("TLS handshakes = %d, want 1", first.TLSHandshakes)
+	}
+	if setup := first.DNS + first.Connect + first.TLSHandshake; first.TimeToFirstByte < setup+10*time.Millisecond {
+		t.Errorf("time to first byte %v is shorter than setup %v plus the handler delay", first.TimeToFirstByte, setup)
+	}
+	if resp.Elapsed < first.TimeToFirstByte {
+		t.Errorf("elapsed %v is shorter than time to first byte %v", resp.Elapsed, first.TimeToFirstByte)
+	}
+
+	resp, err = c.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reused := resp.Timings
+	if !reused.ConnectionReused {
+		t.Error("second request did not reuse the connection")
+	}
+	if reused.DNS != 0 || reused.Connect != 0 || reused.TLSHandshake != 0 || reused.TLSHandshakes != 0 {
+		t.Errorf("reused connection timings %+v, want no DNS, connect or TLS time", reused)
+	}
+	if reused.TimeToFirstByte < 10*time.Millisecond {
+		t.Errorf("time to first byte %v is shorter than the handler delay", reused.TimeToFirstByte)
+	}
+}
+
+func TestFailedHandshakeIsNotTimed(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{
+		MinVersion:       tls.VersionTLS13,
+		CurvePreferences: []tls.CurveID{tls.CurveP521},
+	}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, recorder := withTimingRecorder(context.Background(), time.Now())
+	if _, err := c.dialTLS(ctx, "tcp", strings.TrimPrefix(server.URL, "https://")); err == nil {
+		t.Fatal("handshake succeeded against a server without a shared group")
+	}
+
+	if timings := recorder.result(); timings.TLSHandshakes != 0 || timings.TLSHandshake != 0 {
+		t.Errorf("failed handshake was recorded: %+v", timings)
+	}
+}
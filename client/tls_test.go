@@ -0,0 +1,206 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/rip-zoyo/orbit-tls/profiles"
+)
+
+func newTLSServer(t *testing.T, handler http.Handler) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	return server, roots
+}
+
+func TestNegotiatesX25519MLKEM768(t *testing.T) {
+	groups := make(chan tls.CurveID, 1)
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		groups <- r.TLS.CurveID
+	}))
+
+	c, err := NewWithOptions("Chrome138", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Proto != "HTTP/2.0" {
+		t.Errorf("protocol = %s, want HTTP/2.0", resp.Proto)
+	}
+
+	if group := <-groups; group != tls.X25519MLKEM768 {
+		t.Errorf("server negotiated %v, want X25519MLKEM768", group)
+	}
+
+	conns := c.Connections()
+	if len(conns) != 1 || conns[0].Details == nil {
+		t.Fatalf("expected one tracked connection, got %+v", conns)
+	}
+	details := conns[0].Details
+	if details.NegotiatedGroup != uint16(tls.X25519MLKEM768) {
+		t.Errorf("tracked group = %#x, want %#x", details.NegotiatedGroup, uint16(tls.X25519MLKEM768))
+	}
+	if len(details.KeyShares) == 0 || details.KeyShares[0].Group != uint16(tls.X25519MLKEM768) {
+		t.Errorf("first key share = %+v, want X25519MLKEM768", details.KeyShares)
+	}
+}
+
+func TestResumesTLSSession(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target, _ := url.Parse(server.URL)
+
+	c, err := NewWithOptions("Firefox131", &ClientOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{false, true} {
+		if _, err := c.Get(server.URL); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		c.CloseIdle()
+
+		details, ok := c.tracker.GetConnection(target.Host)
+		if !ok {
+			t.Fatalf("request %d: no tracked connection", i)
+		}
+		if details.PSKOffered != want || details.Resumed != want {
+			t.Errorf("request %d: psk offered %v resumed %v, want %v", i, details.PSKOffered, details.Resumed, want)
+		}
+	}
+
+	cold, err := NewWithOptions("Firefox131", &ClientOptions{RootCAs: roots, DisableSessionResumption: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cold.Get(server.URL); err != nil {
+			t.Fatalf("cold request %d: %v", i, err)
+		}
+		cold.CloseIdle()
+	}
+	if details, _ := cold.tracker.GetConnection(target.Host); details == nil || details.PSKOffered || details.Resumed {
+		t.Errorf("details = %+v, want a full handshake without a PSK", details)
+	}
+}
+
+func TestEarlyDataOverTCP(t *testing.T) {
+	if _, err := NewWithOptions("Chrome138", &ClientOptions{EarlyData: true}); err == nil {
+		t.Error("EarlyData without a QUIC transport was accepted")
+	}
+}
+
+func TestAdvertisesCertCompression(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for name, want := range map[string][]uint16{
+		"Chrome138":  {profiles.CertCompressionBrotli},
+		"Firefox131": {profiles.CertCompressionZlib, profiles.CertCompressionBrotli, profiles.CertCompressionZstd},
+		"Safari18":   {profiles.CertCompressionZlib},
+	} {
+		c, err := NewWithOptions(name, &ClientOptions{RootCAs: roots})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Get(server.URL); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		conns := c.Connections()
+		if len(conns) != 1 || conns[0].Details == nil {
+			t.Fatalf("%s: expected one tracked connection, got %+v", name, conns)
+		}
+		if got := conns[0].Details.CertCompressionAlgorithms; !slices.Equal(got, want) {
+			t.Errorf("%s advertised %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestAdvertisesALPS(t *testing.T) {
+	var mu sync.Mutex
+	var hellos [][]uint16
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.EnableHTTP2 = true
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mu.Lock()
+			hellos = append(hellos, hello.Extensions)
+			mu.Unlock()
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	tests := []struct {
+		profile  string
+		protocol ProtocolMode
+		want     uint16
+	}{
+		{"Chrome131", ProtocolAuto, profiles.ALPSCodepointLegacy},
+		{"Chrome138", ProtocolAuto, profiles.ALPSCodepoint},
+		{"Chrome138", ProtocolHTTP1, 0},
+		{"Firefox131", ProtocolAuto, 0},
+	}
+	for i, tt := range tests {
+		c, err := NewWithOptions(tt.profile, &ClientOptions{RootCAs: roots, Protocol: tt.protocol})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Get(server.URL); err != nil {
+			t.Fatalf("%s: %v", tt.profile, err)
+		}
+
+		mu.Lock()
+		extensions := hellos[i]
+		mu.Unlock()
+		for _, codepoint := range []uint16{profiles.ALPSCodepointLegacy, profiles.ALPSCodepoint} {
+			if sent := slices.Contains(extensions, codepoint); sent != (codepoint == tt.want) {
+				t.Errorf("%s with protocol %d: extension %d sent = %v", tt.profile, tt.protocol, codepoint, sent)
+			}
+		}
+
+		details := c.Connections()[0].Details
+		if details.ALPSCodepoint != tt.want || details.ALPSNegotiated {
+			t.Errorf("%s: tracked codepoint %d negotiated %v, want %d and not negotiated", tt.profile, details.ALPSCodepoint, details.ALPSNegotiated, tt.want)
+		}
+	}
+}
+
+func TestApplicationSettingsPayload(t *testing.T) {
+	c, err := New("Chrome138")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0, 1, 0, 1, 0, 0,
+		0, 2, 0, 0, 0, 0,
+		0, 3, 0, 0, 3, 0xe8,
+		0, 4, 0, 0x60, 0, 0,
+		0, 5, 0, 0, 0x40, 0,
+		0, 6, 0, 4, 0, 0,
+	}
+	if got := c.applicationSettings()["h2"]; !bytes.Equal(got, want) {
+		t.Errorf("h2 settings = %x, want %x", got, want)
+	}
+}
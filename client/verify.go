@@ -0,0 +1,96 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/rip-zoyo/orbit-tls/tracking"
+)
+
+type PinError struct {
+	Host  string
+	Pins  []string
+	Chain []string
+}
+
+func (e *PinError) Error() string {
+	return fmt.Sprintf("no pinned public key for %s in certificate chain", e.Host)
+}
+
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func normalizePins(pins map[string][]string) (map[string][]string, error) {
+	normalized := make(map[string][]string, len(pins))
+	for host, values := range pins {
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		for _, value := range values {
+			encoded := strings.TrimPrefix(strings.TrimSpace(value), "sha256/")
+			sum, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pin for %s: %w", host, err)
+			}
+			if len(sum) != sha256.Size {
+				return nil, fmt.Errorf("invalid pin for %s: expected a SHA-256 hash, got %d bytes", host, len(sum))
+			}
+			normalized[host] = append(normalized[host], "sha256/"+encoded)
+		}
+	}
+	return normalized, nil
+}
+
+func (c *Client) pinsFor(host string) []string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if pins, ok := c.pins[host]; ok {
+		return pins
+	}
+	if _, parent, found := strings.Cut(host, "."); found {
+		return c.pins["*."+parent]
+	}
+	return nil
+}
+
+func (c *Client) verifyConnection(state tls.ConnectionState, details *tracking.ConnectionDetails) error {
+	host := details.ServerName
+	if host == "" {
+		host = state.ServerName
+	}
+
+	if pins := c.pinsFor(host); len(pins) > 0 {
+		if err := checkPins(host, pins, state); err != nil {
+			return err
+		}
+	}
+
+	if c.options.VerifyConnection != nil {
+		return c.options.VerifyConnection(state, details)
+	}
+	return nil
+}
+
+func checkPins(host string, pins []string, state tls.ConnectionState) error {
+	chains := state.VerifiedChains
+	if len(chains) == 0 {
+		chains = [][]*x509.Certificate{state.PeerCertificates}
+	}
+
+	var seen []string
+	for _, chain := range chains {
+		for _, cert := range chain {
+			pin := SPKIPin(cert)
+			for _, want := range pins {
+				if pin == want {
+					return nil
+				}
+			}
+			seen = append(seen, pin)
+		}
+	}
+	return &PinError{Host: host, Pins: pins, Chain: seen}
+}
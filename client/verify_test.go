@@ -0,0 +1,108 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestPinsForWildcard(t *testing.T) {
+	pin := "sha256/" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+	pins, err := normalizePins(map[string][]string{"*.example.com": {pin}, "Exact.Test.": {pin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{pins: pins}
+
+	tests := []struct {
+		host   string
+		pinned bool
+	}{
+		{"api.example.com", true},
+		{"API.Example.com.", true},
+		{"example.com", false},
+		{"a.b.example.com", false},
+		{"exact.test", true},
+		{"sub.exact.test", false},
+	}
+	for _, tt := range tests {
+		if got := len(c.pinsFor(tt.host)) > 0; got != tt.pinned {
+			t.Errorf("pinsFor(%q) pinned = %v, want %v", tt.host, got, tt.pinned)
+		}
+	}
+
+	if _, err := normalizePins(map[string][]string{"example.com": {"sha256/AAAA"}}); err == nil {
+		t.Error("normalizePins accepted a pin that is not a SHA-256 hash")
+	}
+}
+
+func TestCertificatePins(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target, _ := url.Parse(server.URL)
+	endpoint := "https://example.com:" + target.Port()
+	good := SPKIPin(server.Certificate())
+	bad := "sha256/" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	newClient := func(pins ...string) *Client {
+		c, err := NewWithOptions("Chrome138", &ClientOptions{
+			RootCAs: roots,
+			Hosts:   map[string]string{"example.com": target.Hostname()},
+			Pins:    map[string][]string{"example.com": pins},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	if _, err := newClient(bad, good).Get(endpoint); err != nil {
+		t.Fatalf("matching pin: %v", err)
+	}
+
+	_, err := newClient(bad).Get(endpoint)
+	var certErr *CertificateError
+	if !errors.As(err, &certErr) {
+		t.Fatalf("error = %v (%T), want *CertificateError", err, err)
+	}
+	var pinErr *PinError
+	if !errors.As(err, &pinErr) {
+		t.Fatalf("error = %v, want it to wrap *PinError", err)
+	}
+	if pinErr.Host != "example.com" || !slices.Equal(pinErr.Pins, []string{bad}) || !slices.Contains(pinErr.Chain, good) {
+		t.Errorf("PinError = %+v, want host example.com, pins [%s] and the server pin in the chain", pinErr, bad)
+	}
+}
+
+func TestRootCAsAndInsecureSkipVerify(t *testing.T) {
+	server, roots := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name    string
+		options *ClientOptions
+		trusted bool
+	}{
+		{"system roots", nil, false},
+		{"custom roots", &ClientOptions{RootCAs: roots}, true},
+		{"insecure", &ClientOptions{InsecureSkipVerify: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewWithOptions("Firefox131", tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Get(server.URL)
+			if tt.trusted && err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			var certErr *CertificateError
+			if !tt.trusted && !errors.As(err, &certErr) {
+				t.Fatalf("error = %v (%T), want *CertificateError", err, err)
+			}
+		})
+	}
+}
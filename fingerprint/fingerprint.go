@@ -28,7 +28,10 @@ type Data struct {
 	AkamaiFPHash         string        `json:"akamai_fingerprint_hash"`
 	ClientRandom         string        `json:"client_random"`
 	SessionID            string        `json:"session_id"`
+	Protocol             string        `json:"protocol,omitempty"`
+	Cleartext            bool          `json:"cleartext,omitempty"`
 	HTTP2                *HTTP2Data    `json:"http2,omitempty"`
+	HTTP3                *HTTP3Data    `json:"http3,omitempty"`
 }
 
 type Extension struct {
@@ -46,6 +49,11 @@ type HTTP2Data struct {
 	AkamaiFingerprintHash string           `json:"akamai_fingerprint_hash"`
 }
 
+type HTTP3Data struct {
+	ALPN                string            `json:"alpn"`
+	TransportParameters map[string]uint64 `json:"transport_parameters"`
+}
+
 type HeaderPriority struct {
 	Dependency uint32 `json:"dependency"`
 	Exclusive  bool   `json:"exclusive"`
@@ -99,10 +107,13 @@ var extensionNames = map[uint16]string{
 	23:    "session_ticket",
 	27:    "compressed_certificate",
 	35:    "session_ticket_tls",
+	41:    "pre_shared_key",
 	43:    "supported_versions",
 	45:    "psk_key_exchange_modes",
 	51:    "key_share",
 	17513: "application_settings",
+	17613: "application_settings_new",
+	65037: "encrypted_client_hello",
 	65281: "renegotiation_info",
 }
 
@@ -119,14 +130,7 @@ func GenerateJA4(tlsVersion uint16, cipherSuites, extensions, supportedGroups []
 		ver = "11"
 	}
 
-	proto := "d"
-	if len(alpnProtocols) > 0 {
-		if contains(alpnProtocols, "h2") {
-			proto = "h2"
-		} else if contains(alpnProtocols, "http/1.1") {
-			proto = "h1"
-		}
-	}
+	proto := ja4ALPN(alpnProtocols)
 
 	cipherHex := make([]string, len(cipherSuites))
 	for i, c := range cipherSuites {
@@ -153,14 +157,7 @@ func GenerateJA4R(tlsVersion uint16, cipherSuites, extensions, supportedGroups [
 		ver = "11"
 	}
 
-	proto := "d"
-	if len(alpnProtocols) > 0 {
-		if contains(alpnProtocols, "h2") {
-			proto = "h2"
-		} else if contains(alpnProtocols, "http/1.1") {
-			proto = "h1"
-		}
-	}
+	proto := ja4ALPN(alpnProtocols)
 
 	cipherHex := make([]string, len(cipherSuites))
 	for i, c := range cipherSuites {
@@ -279,11 +276,11 @@ func formatUint16Slice(slice []uint16) string {
 	return strings.Join(strs, "-")
 }
 
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
+func ja4ALPN(alpnProtocols []string) string {
+	if len(alpnProtocols) == 0 || alpnProtocols[0] == "" {
+		return "00"
 	}
-	return false
-} 
\ No newline at end of file
+	first := alpnProtocols[0]
+	return string(first[0]) + string(first[len(first)-1])
+}
+ 
\ No newline at end of file
@@ -1,10 +1,17 @@
 module github.com/rip-zoyo/orbit-tls
 
-go 1.21
+go 1.27
 
 require (
-	golang.org/x/crypto v0.18.0
-	golang.org/x/net v0.20.0
+	github.com/andybalholm/brotli v1.0.6
+	github.com/klauspost/compress v1.18.0
+	github.com/quic-go/qpack v0.6.0
+	github.com/quic-go/quic-go v0.59.1
+	github.com/refraction-networking/utls v1.8.2
+	golang.org/x/crypto v0.41.0
+	golang.org/x/net v0.43.0
+	golang.org/x/text v0.28.0
+	software.sslmate.com/src/go-pkcs12 v0.7.3
 )
 
-require golang.org/x/text v0.14.0 // indirect
+require golang.org/x/sys v0.35.0 // indirect
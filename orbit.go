@@ -1,9 +1,68 @@
 package orbit
 
-import "github.com/rip-zoyo/orbit-tls/client"
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+
+	"github.com/rip-zoyo/orbit-tls/client"
+	"github.com/rip-zoyo/orbit-tls/fingerprint"
+)
 
 type Client = client.Client
 type Response = client.Response
+type ClientOptions = client.ClientOptions
+type ProtocolMode = client.ProtocolMode
+type RequestOptions = client.RequestOptions
+type RequestIntent = client.RequestIntent
+type HeaderMode = client.HeaderMode
+type IPPreference = client.IPPreference
+type Multipart = client.Multipart
+type Timings = client.Timings
+type ConnectionInfo = client.ConnectionInfo
+type Middleware = client.Middleware
+type RoundTripperFunc = client.RoundTripperFunc
+type HandshakeError = client.HandshakeError
+type CertificateError = client.CertificateError
+type ProtocolError = client.ProtocolError
+type ProxyError = client.ProxyError
+type TimeoutError = client.TimeoutError
+type StatusError = client.StatusError
+type PinError = client.PinError
+type ClientCertificate = client.ClientCertificate
+
+const (
+	ProtocolAuto  = client.ProtocolAuto
+	ProtocolHTTP1 = client.ProtocolHTTP1
+	ProtocolHTTP2 = client.ProtocolHTTP2
+	ProtocolH2C   = client.ProtocolH2C
+)
+
+const (
+	HeaderMerge       = client.HeaderMerge
+	HeaderReplace     = client.HeaderReplace
+	HeaderProfileOnly = client.HeaderProfileOnly
+)
+
+const (
+	IPDefault  = client.IPDefault
+	PreferIPv4 = client.PreferIPv4
+	PreferIPv6 = client.PreferIPv6
+	IPv4Only   = client.IPv4Only
+	IPv6Only   = client.IPv6Only
+)
+
+const (
+	IntentAuto       = client.IntentAuto
+	IntentNavigate   = client.IntentNavigate
+	IntentFetch      = client.IntentFetch
+	IntentImage      = client.IntentImage
+	IntentScript     = client.IntentScript
+	IntentIframe     = client.IntentIframe
+	IntentFormSubmit = client.IntentFormSubmit
+)
 
 var Chrome120 = client.Chrome120
 var Chrome131 = client.Chrome131
@@ -26,4 +85,32 @@ var Brave138 = client.Brave138
 
 func New(profileName string) (*Client, error) {
 	return client.New(profileName)
-} 
\ No newline at end of file
+}
+
+func NewWithOptions(profileName string, options *ClientOptions) (*Client, error) {
+	return client.NewWithOptions(profileName, options)
+}
+
+func NewMultipart() *Multipart {
+	return client.NewMultipart()
+}
+
+func SPKIPin(cert *x509.Certificate) string {
+	return client.SPKIPin(cert)
+}
+
+func LoadPKCS12(data []byte, password string) (tls.Certificate, error) {
+	return client.LoadPKCS12(data, password)
+}
+
+func ProxyFromEnvironment(req *http.Request) (*url.URL, error) {
+	return client.ProxyFromEnvironment(req)
+}
+
+func ProxyURL(fixedURL *url.URL) func(*http.Request) (*url.URL, error) {
+	return client.ProxyURL(fixedURL)
+}
+
+func FingerprintFromContext(ctx context.Context) *fingerprint.Data {
+	return client.FingerprintFromContext(ctx)
+}
\ No newline at end of file
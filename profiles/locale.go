@@ -0,0 +1,103 @@
+package profiles
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+func Locales(tags ...string) Option {
+	return func(o *options) {
+		o.locales = append(o.locales, tags...)
+	}
+}
+
+func AcceptLanguage(family string, locales []string) (string, error) {
+	var tags []string
+	for _, locale := range locales {
+		tag, err := language.Parse(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+		if err != nil {
+			return "", fmt.Errorf("invalid locale %q: %w", locale, err)
+		}
+		tags = appendUnique(tags, tag.String())
+	}
+	if len(tags) == 0 {
+		return "", fmt.Errorf("no locales given")
+	}
+
+	switch family {
+	case FamilyFirefox:
+		return firefoxAcceptLanguage(tags), nil
+	case FamilyChromium:
+		return chromiumAcceptLanguage(expandBaseLanguages(tags)), nil
+	default:
+		return chromiumAcceptLanguage(tags), nil
+	}
+}
+
+func chromiumAcceptLanguage(tags []string) string {
+	parts := make([]string, len(tags))
+	q := 10
+	for i, tag := range tags {
+		if i == 0 {
+			parts[i] = tag
+			continue
+		}
+		q = max(q-1, 1)
+		parts[i] = fmt.Sprintf("%s;q=0.%d", tag, q)
+	}
+	return strings.Join(parts, ",")
+}
+
+func firefoxAcceptLanguage(tags []string) string {
+	parts := make([]string, len(tags))
+	step := 1 / float64(len(tags))
+	for i, tag := range tags {
+		if i == 0 {
+			parts[i] = tag
+			continue
+		}
+		q := math.Round((1-step*float64(i))*10) / 10
+		parts[i] = tag + ";q=" + strconv.FormatFloat(max(q, 0.1), 'f', 1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+func expandBaseLanguages(tags []string) []string {
+	var expanded []string
+	for i, tag := range tags {
+		expanded = appendUnique(expanded, tag)
+
+		base, _, found := strings.Cut(tag, "-")
+		if !found {
+			continue
+		}
+		if i+1 < len(tags) && strings.HasPrefix(tags[i+1], base+"-") {
+			continue
+		}
+		if contains(tags, base) {
+			continue
+		}
+		expanded = appendUnique(expanded, base)
+	}
+	return expanded
+}
+
+func appendUnique(list []string, value string) []string {
+	if contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package profiles
+
+import "testing"
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		family  string
+		locales []string
+		want    string
+	}{
+		{FamilyChromium, []string{"en-US"}, "en-US,en;q=0.9"},
+		{FamilyChromium, []string{"en"}, "en"},
+		{FamilyChromium, []string{"de_DE", "en-US"}, "de-DE,de;q=0.9,en-US;q=0.8,en;q=0.7"},
+		{FamilyChromium, []string{"en-US", "en-GB"}, "en-US,en-GB;q=0.9,en;q=0.8"},
+		{FamilyChromium, []string{"fr-FR", "fr"}, "fr-FR,fr;q=0.9"},
+		{FamilyChromium, []string{"en-us", "EN-US"}, "en-US,en;q=0.9"},
+		{FamilyChromium, []string{"en", "fr", "de", "es", "it", "pt", "nl", "sv", "da", "fi", "nb", "pl"},
+			"en,fr;q=0.9,de;q=0.8,es;q=0.7,it;q=0.6,pt;q=0.5,nl;q=0.4,sv;q=0.3,da;q=0.2,fi;q=0.1,nb;q=0.1,pl;q=0.1"},
+		{FamilyFirefox, []string{"en-US"}, "en-US"},
+		{FamilyFirefox, []string{"en-US", "en"}, "en-US,en;q=0.5"},
+		{FamilyFirefox, []string{"fr", "en-US", "en"}, "fr,en-US;q=0.7,en;q=0.3"},
+		{FamilyFirefox, []string{"de-DE", "de", "en-US", "en"}, "de-DE,de;q=0.8,en-US;q=0.5,en;q=0.3"},
+		{FamilySafari, []string{"en-US"}, "en-US"},
+		{FamilySafari, []string{"ja-JP", "en-US"}, "ja-JP,en-US;q=0.9"},
+	}
+
+	for _, tt := range tests {
+		got, err := AcceptLanguage(tt.family, tt.locales)
+		if err != nil {
+			t.Errorf("%s %v: %v", tt.family, tt.locales, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %v = %q, want %q", tt.family, tt.locales, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptLanguageErrors(t *testing.T) {
+	for _, locales := range [][]string{nil, {"not a locale!"}} {
+		if got, err := AcceptLanguage(FamilyChromium, locales); err == nil {
+			t.Errorf("%q = %q, want an error", locales, got)
+		}
+	}
+	if _, err := Get("Chrome138", Locales("??")); err == nil {
+		t.Error("Get accepted an invalid locale")
+	}
+}
+
+func TestLocalesOption(t *testing.T) {
+	profile, err := Get("Firefox131", OS(OSLinux), Locales("de-DE", "en-US"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.AcceptLanguage != "de-DE,en-US;q=0.5" {
+		t.Errorf("Accept-Language = %q", profile.AcceptLanguage)
+	}
+
+	base, _ := Get("Firefox131")
+	if base.AcceptLanguage == profile.AcceptLanguage {
+		t.Error("Locales changed the base profile")
+	}
+}
@@ -0,0 +1,235 @@
+package profiles
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	OSWindows  = "Windows"
+	OSMacOS    = "macOS"
+	OSLinux    = "Linux"
+	OSAndroid  = "Android"
+	OSiOS      = "iOS"
+	OSChromeOS = "ChromeOS"
+)
+
+type Option func(*options)
+
+type options struct {
+	os      string
+	locales []string
+}
+
+func OS(name string) Option {
+	return func(o *options) {
+		o.os = name
+	}
+}
+
+type osVariant struct {
+	chromiumPlatform string
+	firefoxPlatform  string
+	hintPlatform     string
+	platformVersion  string
+	arch             string
+	bitness          string
+	model            string
+	mobile           bool
+}
+
+var osVariants = map[string]osVariant{
+	OSWindows: {
+		chromiumPlatform: "Windows NT 10.0; Win64; x64",
+		firefoxPlatform:  "Windows NT 10.0; Win64; x64",
+		hintPlatform:     `"Windows"`,
+		platformVersion:  `"10.0.0"`,
+		arch:             `"x86"`,
+		bitness:          `"64"`,
+		model:            `""`,
+	},
+	OSMacOS: {
+		chromiumPlatform: "Macintosh; Intel Mac OS X 10_15_7",
+		firefoxPlatform:  "Macintosh; Intel Mac OS X 10.15",
+		hintPlatform:     `"macOS"`,
+		platformVersion:  `"15.5.0"`,
+		arch:             `"arm"`,
+		bitness:          `"64"`,
+		model:            `""`,
+	},
+	OSLinux: {
+		chromiumPlatform: "X11; Linux x86_64",
+		firefoxPlatform:  "X11; Linux x86_64",
+		hintPlatform:     `"Linux"`,
+		platformVersion:  `"6.8.0"`,
+		arch:             `"x86"`,
+		bitness:          `"64"`,
+		model:            `""`,
+	},
+	OSChromeOS: {
+		chromiumPlatform: "X11; CrOS x86_64 14541.0.0",
+		firefoxPlatform:  "X11; CrOS x86_64 14541.0.0",
+		hintPlatform:     `"Chrome OS"`,
+		platformVersion:  `"14541.0.0"`,
+		arch:             `"x86"`,
+		bitness:          `"64"`,
+		model:            `""`,
+	},
+	OSAndroid: {
+		chromiumPlatform: "Linux; Android 10; K",
+		firefoxPlatform:  "Android 14; Mobile",
+		hintPlatform:     `"Android"`,
+		platformVersion:  `"14.0.0"`,
+		arch:             `""`,
+		bitness:          `""`,
+		model:            `"SM-G998B"`,
+		mobile:           true,
+	},
+}
+
+var safariCounterparts = map[string]string{
+	"Safari17":    "SafariiOS",
+	"Safari18":    "SafariiOS18",
+	"SafariiOS":   "Safari17",
+	"SafariiOS18": "Safari18",
+}
+
+var (
+	uaPlatformPattern     = regexp.MustCompile(`^Mozilla/5\.0 \([^)]*\)`)
+	firefoxRVPattern      = regexp.MustCompile(`rv:([0-9.]+)`)
+	firefoxVersionPattern = regexp.MustCompile(`Firefox/([0-9.]+)`)
+	chromeVersionPattern  = regexp.MustCompile(`Chrome/([0-9.]+)`)
+	edgeVersionPattern    = regexp.MustCompile(`Edg/([0-9.]+)`)
+	chromiumBrandPattern  = regexp.MustCompile(`"Chromium";v="([0-9.]+)"`)
+	iosVersionPattern     = regexp.MustCompile(`iPhone OS ([0-9_]+)`)
+	webkitVersionPattern  = regexp.MustCompile(`Version/[0-9.]+ Mobile/\S+`)
+)
+
+func normalizeOS(name string) (string, bool) {
+	for _, os := range []string{OSWindows, OSMacOS, OSLinux, OSAndroid, OSiOS, OSChromeOS} {
+		if strings.EqualFold(name, os) {
+			return os, true
+		}
+	}
+	return "", false
+}
+
+func withOS(base *Profile, name string) (*Profile, error) {
+	os, ok := normalizeOS(name)
+	if !ok {
+		return nil, fmt.Errorf("unsupported operating system: %s", name)
+	}
+	if os == base.OS {
+		return base, nil
+	}
+
+	if base.Family == FamilySafari {
+		if os != OSMacOS && os != OSiOS {
+			return nil, fmt.Errorf("%s is not available on %s", base.Name, os)
+		}
+		profile := profiles[safariCounterparts[base.Name]].clone()
+		profile.Name = base.Name
+		return profile, nil
+	}
+
+	if os == OSiOS {
+		return webkitVariant(base), nil
+	}
+
+	profile := base.clone()
+	profile.OS = os
+	variant := osVariants[os]
+
+	switch profile.Family {
+	case FamilyChromium:
+		platform := uaPlatformPattern.ReplaceAllString(profile.UserAgent, "Mozilla/5.0 ("+variant.chromiumPlatform+")")
+		platform = strings.Replace(platform, " Mobile Safari/", " Safari/", 1)
+		if variant.mobile {
+			platform = strings.Replace(platform, " Safari/", " Mobile Safari/", 1)
+		}
+		profile.UserAgent = platform
+
+		if _, ok := profile.SecHeaders["sec-ch-ua-platform"]; ok {
+			profile.SecHeaders["sec-ch-ua-platform"] = variant.hintPlatform
+		}
+		if _, ok := profile.SecHeaders["sec-ch-ua-mobile"]; ok {
+			profile.SecHeaders["sec-ch-ua-mobile"] = "?0"
+			if variant.mobile {
+				profile.SecHeaders["sec-ch-ua-mobile"] = "?1"
+			}
+		}
+		setHint(profile.ClientHints, "sec-ch-ua-platform-version", variant.platformVersion)
+		setHint(profile.ClientHints, "sec-ch-ua-arch", variant.arch)
+		setHint(profile.ClientHints, "sec-ch-ua-bitness", variant.bitness)
+		setHint(profile.ClientHints, "sec-ch-ua-model", variant.model)
+
+	case FamilyFirefox:
+		rv := submatch(firefoxRVPattern, profile.UserAgent)
+		version := submatch(firefoxVersionPattern, profile.UserAgent)
+		gecko := "20100101"
+		if variant.mobile {
+			gecko = rv
+		}
+		profile.UserAgent = fmt.Sprintf("Mozilla/5.0 (%s; rv:%s) Gecko/%s Firefox/%s", variant.firefoxPlatform, rv, gecko, version)
+	}
+
+	return profile, nil
+}
+
+func webkitVariant(base *Profile) *Profile {
+	profile := profiles["SafariiOS18"].clone()
+	profile.Name = base.Name
+
+	iosVersion := submatch(iosVersionPattern, profile.UserAgent)
+	prefix := "Mozilla/5.0 (iPhone; CPU iPhone OS " + iosVersion + " like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) "
+
+	switch {
+	case base.Family == FamilyFirefox:
+		version := submatch(firefoxVersionPattern, base.UserAgent)
+		profile.UserAgent = prefix + "FxiOS/" + version + " Mobile/15E148 Safari/605.1.15"
+	case strings.Contains(base.UserAgent, "Edg/"):
+		profile.UserAgent = prefix + "EdgiOS/" + submatch(edgeVersionPattern, base.UserAgent) + " Mobile/15E148 Safari/605.1.15"
+	case strings.Contains(base.Name, "Brave"):
+	default:
+		version := submatch(chromiumBrandPattern, base.ClientHints["sec-ch-ua-full-version-list"])
+		if version == "" {
+			version = submatch(chromeVersionPattern, base.UserAgent)
+		}
+		profile.UserAgent = webkitVersionPattern.ReplaceAllString(profile.UserAgent, "CriOS/"+version+" Mobile/15E148")
+	}
+
+	return profile
+}
+
+func (p *Profile) clone() *Profile {
+	profile := *p
+	profile.SecHeaders = copyHints(p.SecHeaders)
+	profile.ClientHints = copyHints(p.ClientHints)
+	return &profile
+}
+
+func copyHints(hints map[string]string) map[string]string {
+	if hints == nil {
+		return nil
+	}
+	out := make(map[string]string, len(hints))
+	for key, value := range hints {
+		out[key] = value
+	}
+	return out
+}
+
+func setHint(hints map[string]string, name, value string) {
+	if _, ok := hints[name]; ok {
+		hints[name] = value
+	}
+}
+
+func submatch(pattern *regexp.Regexp, value string) string {
+	match := pattern.FindStringSubmatch(value)
+	if len(match) < 2 {
+		return ""
+	}
+	return match[1]
+}
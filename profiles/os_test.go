@@ -0,0 +1,134 @@
+package profiles
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOSVariants(t *testing.T) {
+	tests := []struct {
+		profile  string
+		os       string
+		family   string
+		ua       []string
+		sec      map[string]string
+		hints    map[string]string
+		noHints  bool
+		sameJA3  string
+		notFound bool
+	}{
+		{
+			profile: "Chrome138", os: "macOS", family: FamilyChromium,
+			ua:      []string{"(Macintosh; Intel Mac OS X 10_15_7)", "Chrome/138.0.0.0 Safari/537.36"},
+			sec:     map[string]string{"sec-ch-ua-platform": `"macOS"`, "sec-ch-ua-mobile": "?0"},
+			hints:   map[string]string{"sec-ch-ua-arch": `"arm"`, "sec-ch-ua-platform-version": `"15.5.0"`},
+			sameJA3: "Chrome138",
+		},
+		{
+			profile: "Chrome138", os: "android", family: FamilyChromium,
+			ua:      []string{"(Linux; Android 10; K)", "Mobile Safari/537.36"},
+			sec:     map[string]string{"sec-ch-ua-platform": `"Android"`, "sec-ch-ua-mobile": "?1"},
+			hints:   map[string]string{"sec-ch-ua-model": `"SM-G998B"`, "sec-ch-ua-arch": `""`},
+			sameJA3: "Chrome138",
+		},
+		{
+			profile: "Firefox131", os: "Linux", family: FamilyFirefox,
+			ua:      []string{"(X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0"},
+			noHints: true, sameJA3: "Firefox131",
+		},
+		{
+			profile: "Firefox131", os: "Android", family: FamilyFirefox,
+			ua:      []string{"(Android 14; Mobile; rv:131.0) Gecko/131.0 Firefox/131.0"},
+			noHints: true, sameJA3: "Firefox131",
+		},
+		{
+			profile: "Chrome138", os: "iOS", family: FamilySafari,
+			ua:      []string{"(iPhone; CPU iPhone OS", "CriOS/138.0.7204.101 Mobile/15E148"},
+			noHints: true, sameJA3: "SafariiOS18",
+		},
+		{
+			profile: "Firefox131", os: "iOS", family: FamilySafari,
+			ua:      []string{"(iPhone; CPU iPhone OS", "FxiOS/131.0 Mobile/15E148"},
+			noHints: true, sameJA3: "SafariiOS18",
+		},
+		{
+			profile: "Safari18", os: "iOS", family: FamilySafari,
+			ua:      []string{"(iPhone; CPU iPhone OS", "Version/18.1 Mobile/15E148"},
+			noHints: true, sameJA3: "SafariiOS18",
+		},
+		{
+			profile: "SafariiOS18", os: "macOS", family: FamilySafari,
+			ua:      []string{"(Macintosh; Intel Mac OS X 10_15_7)", "Version/18.1.1 Safari/605.1.15"},
+			noHints: true, sameJA3: "Safari18",
+		},
+		{profile: "Safari18", os: "Windows", notFound: true},
+		{profile: "Chrome138", os: "BeOS", notFound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile+"/"+tt.os, func(t *testing.T) {
+			profile, err := Get(tt.profile, OS(tt.os))
+			if tt.notFound {
+				if err == nil {
+					t.Fatalf("got %s on %s, want an error", profile.Name, tt.os)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if profile.Name != tt.profile {
+				t.Errorf("name = %s, want %s", profile.Name, tt.profile)
+			}
+			if profile.Family != tt.family {
+				t.Errorf("family = %s, want %s", profile.Family, tt.family)
+			}
+			for _, part := range tt.ua {
+				if !strings.Contains(profile.UserAgent, part) {
+					t.Errorf("user agent %q does not contain %q", profile.UserAgent, part)
+				}
+			}
+			for name, want := range tt.sec {
+				if got := profile.SecHeaders[name]; got != want {
+					t.Errorf("%s = %s, want %s", name, got, want)
+				}
+			}
+			for name, want := range tt.hints {
+				if got := profile.ClientHints[name]; got != want {
+					t.Errorf("%s = %s, want %s", name, got, want)
+				}
+			}
+			if tt.noHints && (len(profile.SecHeaders) > 0 || len(profile.ClientHints) > 0) {
+				t.Errorf("%s on %s sends client hints %v %v", tt.profile, tt.os, profile.SecHeaders, profile.ClientHints)
+			}
+
+			want, _ := Get(tt.sameJA3)
+			if profile.JA3 != want.JA3 {
+				t.Errorf("JA3 differs from %s", tt.sameJA3)
+			}
+			for key, value := range want.HTTP2Settings {
+				if profile.HTTP2Settings[key] != value {
+					t.Errorf("HTTP/2 setting %s = %d, want %d from %s", key, profile.HTTP2Settings[key], value, tt.sameJA3)
+				}
+			}
+		})
+	}
+}
+
+func TestOSVariantLeavesBaseUnchanged(t *testing.T) {
+	base, _ := Get("Chrome138")
+	userAgent := base.UserAgent
+	platform := base.SecHeaders["sec-ch-ua-platform"]
+	arch := base.ClientHints["sec-ch-ua-arch"]
+
+	for _, os := range []string{OSMacOS, OSLinux, OSAndroid, OSiOS, OSChromeOS} {
+		if _, err := Get("Chrome138", OS(os)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if base.UserAgent != userAgent || base.SecHeaders["sec-ch-ua-platform"] != platform || base.ClientHints["sec-ch-ua-arch"] != arch {
+		t.Error("building OS variants changed the base profile")
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 type Profile struct {
 	Name                string              `json:"name"`
+	Family              string              `json:"family"`
+	OS                  string              `json:"os"`
 	UserAgent           string              `json:"user_agent"`
 	AcceptLanguage      string              `json:"accept_language"`
 	AcceptEncoding      string              `json:"accept_encoding"`
@@ -20,13 +22,36 @@ type Profile struct {
 	HTTP2Settings       map[string]uint32   `json:"http2_settings"`
 	HeaderOrder         []string            `json:"header_order"`
 	PseudoHeaderOrder   []string            `json:"pseudo_header_order"`
+	CookieCrumbling     bool                `json:"cookie_crumbling"`
 	SupportedGroups     []uint16            `json:"supported_groups"`
 	ALPNProtocols       []string            `json:"alpn_protocols"`
 	SecHeaders          map[string]string   `json:"sec_headers"`
+	ClientHints         map[string]string   `json:"client_hints"`
+	CertCompressionAlgorithms []uint16      `json:"cert_compression_algorithms"`
+	ALPSCodepoint       uint16              `json:"alps_codepoint"`
+	ALPSProtocols       []string            `json:"alps_protocols"`
+	QUICTransportParameters map[string]uint64 `json:"quic_transport_parameters"`
 }
 
 
 
+const (
+	FamilyChromium = "chromium"
+	FamilyFirefox  = "firefox"
+	FamilySafari   = "safari"
+)
+
+const (
+	CertCompressionZlib   uint16 = 1
+	CertCompressionBrotli uint16 = 2
+	CertCompressionZstd   uint16 = 3
+)
+
+const (
+	ALPSCodepointLegacy uint16 = 17513
+	ALPSCodepoint       uint16 = 17613
+)
+
 type TLSVersions struct {
 	Min uint16 `json:"min"`
 	Max uint16 `json:"max"`
@@ -35,6 +60,8 @@ type TLSVersions struct {
 var profiles = map[string]*Profile{
 	"Chrome120": {
 		Name:           "Chrome120",
+		Family:         FamilyChromium,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -77,22 +104,32 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":authority", ":scheme", ":path", "cache-control", "sec-ch-ua",
-			"sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests",
-			"user-agent", "accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
-			"sec-fetch-dest", "accept-encoding", "accept-language",
+			"sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"sec-ch-ua-platform-version", "sec-ch-ua-arch", "sec-ch-ua-bitness", "sec-ch-ua-model",
+			"sec-ch-ua-full-version-list", "upgrade-insecure-requests",
+			"user-agent", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
+			"sec-fetch-dest", "referer", "accept-encoding", "accept-language",
 		},
 		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: chromiumQUICTransportParameters(),
 		SecHeaders: map[string]string{
 			"sec-ch-ua":          `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`,
 			"sec-ch-ua-mobile":   "?0",
 			"sec-ch-ua-platform": `"Windows"`,
 		},
+		ClientHints: windowsClientHints(`"Not_A Brand";v="8.0.0.0", "Chromium";v="120.0.6099.130", "Google Chrome";v="120.0.6099.130"`),
+		CertCompressionAlgorithms: []uint16{CertCompressionBrotli},
+		ALPSCodepoint:     ALPSCodepointLegacy,
+		ALPSProtocols:     []string{"h2"},
 	},
 
 	"Chrome131": {
 		Name:           "Chrome131",
+		Family:         FamilyChromium,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -135,22 +172,32 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":authority", ":scheme", ":path", "cache-control", "sec-ch-ua",
-			"sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests",
-			"user-agent", "accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
-			"sec-fetch-dest", "accept-encoding", "accept-language",
+			"sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"sec-ch-ua-platform-version", "sec-ch-ua-arch", "sec-ch-ua-bitness", "sec-ch-ua-model",
+			"sec-ch-ua-full-version-list", "upgrade-insecure-requests",
+			"user-agent", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
+			"sec-fetch-dest", "referer", "accept-encoding", "accept-language", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: chromiumQUICTransportParameters(),
 		SecHeaders: map[string]string{
 			"sec-ch-ua":          `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`,
 			"sec-ch-ua-mobile":   "?0",
 			"sec-ch-ua-platform": `"Windows"`,
 		},
+		ClientHints: windowsClientHints(`"Google Chrome";v="131.0.6778.86", "Chromium";v="131.0.6778.86", "Not_A Brand";v="24.0.0.0"`),
+		CertCompressionAlgorithms: []uint16{CertCompressionBrotli},
+		ALPSCodepoint:     ALPSCodepointLegacy,
+		ALPSProtocols:     []string{"h2"},
 	},
 
 	"Chrome138": {
 		Name:           "Chrome138",
+		Family:         FamilyChromium,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -175,6 +222,7 @@ var profiles = map[string]*Profile{
 			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
 		},
 		CurvePreferences: []tls.CurveID{
+			tls.X25519MLKEM768,
 			tls.X25519,
 			tls.CurveP256,
 			tls.CurveP384,
@@ -193,22 +241,32 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":authority", ":scheme", ":path", "sec-ch-ua",
-			"sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests",
-			"user-agent", "accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
-			"sec-fetch-dest", "accept-encoding", "accept-language", "priority",
+			"sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"sec-ch-ua-platform-version", "sec-ch-ua-arch", "sec-ch-ua-bitness", "sec-ch-ua-model",
+			"sec-ch-ua-full-version-list", "upgrade-insecure-requests",
+			"user-agent", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
+			"sec-fetch-dest", "referer", "accept-encoding", "accept-language", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{4588, 29, 23, 24},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: chromiumQUICTransportParameters(),
 		SecHeaders: map[string]string{
 			"sec-ch-ua":          `"Google Chrome";v="138", "Chromium";v="138", "Not_A Brand";v="24"`,
 			"sec-ch-ua-mobile":   "?0",
 			"sec-ch-ua-platform": `"Windows"`,
 		},
+		ClientHints: windowsClientHints(`"Google Chrome";v="138.0.7204.101", "Chromium";v="138.0.7204.101", "Not_A Brand";v="24.0.0.0"`),
+		CertCompressionAlgorithms: []uint16{CertCompressionBrotli},
+		ALPSCodepoint:     ALPSCodepoint,
+		ALPSProtocols:     []string{"h2"},
 	},
 
 	"Firefox121": {
 		Name:           "Firefox121",
+		Family:         FamilyFirefox,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
 		AcceptLanguage: "en-US,en;q=0.5",
 		AcceptEncoding: "gzip, deflate, br",
@@ -254,21 +312,28 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":path", ":authority", ":scheme", "user-agent", "accept",
-			"accept-language", "accept-encoding", "dnt", "connection", "upgrade-insecure-requests",
+			"accept-language", "accept-encoding", "referer", "origin", "dnt", "connection",
+			"upgrade-insecure-requests", "sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site", "sec-fetch-user",
 		},
 		PseudoHeaderOrder: []string{":method", ":path", ":authority", ":scheme"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24, 25, 256, 257},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
-		SecHeaders:        map[string]string{},
+		QUICTransportParameters: firefoxQUICTransportParameters(),
+		SecHeaders: map[string]string{
+			"DNT": "1",
+		},
 	},
 
 	"Firefox131": {
 		Name:           "Firefox131",
+		Family:         FamilyFirefox,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
 		AcceptLanguage: "en-US,en;q=0.5",
 		AcceptEncoding: "gzip, deflate, br",
 		Accept:         "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
-		JA3:            "771,4865-4867-4866-49195-49199-52393-52392-49196-49200-49162-49161-49171-49172-51-57-47-53,0-23-65281-10-11-35-16-5-51-43-13-45-28-65037,29-23-24-25-256-257,0",
+		JA3:            "771,4865-4867-4866-49195-49199-52393-52392-49196-49200-49162-49161-49171-49172-51-57-47-53,0-23-65281-10-11-35-16-5-51-43-13-45-28-27-65037,29-23-24-25-256-257,0",
 		TLSVersion:     TLSVersions{Min: tls.VersionTLS12, Max: tls.VersionTLS13},
 		CipherSuites: []uint16{
 			tls.TLS_AES_128_GCM_SHA256,
@@ -295,7 +360,7 @@ var profiles = map[string]*Profile{
 			tls.CurveP384,
 			tls.CurveP521,
 		},
-		Extensions: []uint16{0, 23, 65281, 10, 11, 35, 16, 5, 51, 43, 13, 45, 28, 65037},
+		Extensions: []uint16{0, 23, 65281, 10, 11, 35, 16, 5, 51, 43, 13, 45, 28, 27, 65037},
 		SignatureAlgorithms: []uint16{
 			0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601,
 		},
@@ -309,16 +374,22 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":path", ":authority", ":scheme", "user-agent", "accept",
-			"accept-language", "accept-encoding", "dnt", "connection", "upgrade-insecure-requests",
+			"accept-language", "accept-encoding", "referer", "origin", "dnt", "connection",
+			"upgrade-insecure-requests", "sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site", "sec-fetch-user", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":path", ":authority", ":scheme"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24, 25, 256, 257},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		CertCompressionAlgorithms: []uint16{CertCompressionZlib, CertCompressionBrotli, CertCompressionZstd},
+		QUICTransportParameters: firefoxQUICTransportParameters(),
 		SecHeaders:        map[string]string{},
 	},
 
 	"Safari17": {
 		Name:           "Safari17",
+		Family:         FamilySafari,
+		OS:             OSMacOS,
 		UserAgent:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2.1 Safari/605.1.15",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -363,15 +434,21 @@ var profiles = map[string]*Profile{
 		HeaderOrder: []string{
 			":method", ":scheme", ":path", ":authority", "user-agent", "accept",
 			"accept-language", "accept-encoding", "connection", "upgrade-insecure-requests",
+			"sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "referer", "origin",
 		},
 		PseudoHeaderOrder: []string{":method", ":scheme", ":path", ":authority"},
+		CookieCrumbling:   false,
 		SupportedGroups:   []uint16{29, 23, 24, 25},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: safariQUICTransportParameters(),
 		SecHeaders:        map[string]string{},
+		CertCompressionAlgorithms: []uint16{CertCompressionZlib},
 	},
 
 	"Safari18": {
 		Name:           "Safari18",
+		Family:         FamilySafari,
+		OS:             OSMacOS,
 		UserAgent:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.1.1 Safari/605.1.15",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -416,15 +493,21 @@ var profiles = map[string]*Profile{
 		HeaderOrder: []string{
 			":method", ":scheme", ":path", ":authority", "user-agent", "accept",
 			"accept-language", "accept-encoding", "connection", "upgrade-insecure-requests",
+			"sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "referer", "origin", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":scheme", ":path", ":authority"},
+		CookieCrumbling:   false,
 		SupportedGroups:   []uint16{29, 23, 24, 25},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: safariQUICTransportParameters(),
 		SecHeaders:        map[string]string{},
+		CertCompressionAlgorithms: []uint16{CertCompressionZlib},
 	},
 
 	"Edge120": {
 		Name:           "Edge120",
+		Family:         FamilyChromium,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -467,22 +550,32 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":authority", ":scheme", ":path", "cache-control", "sec-ch-ua",
-			"sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests",
-			"user-agent", "accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
-			"sec-fetch-dest", "accept-encoding", "accept-language",
+			"sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"sec-ch-ua-platform-version", "sec-ch-ua-arch", "sec-ch-ua-bitness", "sec-ch-ua-model",
+			"sec-ch-ua-full-version-list", "upgrade-insecure-requests",
+			"user-agent", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
+			"sec-fetch-dest", "referer", "accept-encoding", "accept-language",
 		},
 		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: chromiumQUICTransportParameters(),
 		SecHeaders: map[string]string{
 			"sec-ch-ua":          `"Not_A Brand";v="8", "Chromium";v="120", "Microsoft Edge";v="120"`,
 			"sec-ch-ua-mobile":   "?0",
 			"sec-ch-ua-platform": `"Windows"`,
 		},
+		ClientHints: windowsClientHints(`"Not_A Brand";v="8.0.0.0", "Chromium";v="120.0.6099.130", "Microsoft Edge";v="120.0.2210.91"`),
+		CertCompressionAlgorithms: []uint16{CertCompressionBrotli},
+		ALPSCodepoint:     ALPSCodepointLegacy,
+		ALPSProtocols:     []string{"h2"},
 	},
 
 	"MullvadBrowser": {
 		Name:           "MullvadBrowser",
+		Family:         FamilyFirefox,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; rv:109.0) Gecko/20100101 Firefox/115.0",
 		AcceptLanguage: "en-US,en;q=0.5",
 		AcceptEncoding: "gzip, deflate, br",
@@ -528,16 +621,21 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":path", ":authority", ":scheme", "user-agent", "accept",
-			"accept-language", "accept-encoding", "dnt", "connection", "upgrade-insecure-requests",
+			"accept-language", "accept-encoding", "referer", "origin", "dnt", "connection",
+			"upgrade-insecure-requests", "sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site", "sec-fetch-user",
 		},
 		PseudoHeaderOrder: []string{":method", ":path", ":authority", ":scheme"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24, 25, 256, 257},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: firefoxQUICTransportParameters(),
 		SecHeaders:        map[string]string{},
 	},
 
 	"SafariiOS": {
 		Name:           "SafariiOS",
+		Family:         FamilySafari,
+		OS:             OSiOS,
 		UserAgent:      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -582,15 +680,21 @@ var profiles = map[string]*Profile{
 		HeaderOrder: []string{
 			":method", ":scheme", ":path", ":authority", "user-agent", "accept",
 			"accept-language", "accept-encoding", "connection", "upgrade-insecure-requests",
+			"sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "referer", "origin",
 		},
 		PseudoHeaderOrder: []string{":method", ":scheme", ":path", ":authority"},
+		CookieCrumbling:   false,
 		SupportedGroups:   []uint16{29, 23, 24, 25},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: safariQUICTransportParameters(),
 		SecHeaders:        map[string]string{},
+		CertCompressionAlgorithms: []uint16{CertCompressionZlib},
 	},
 
 	"SafariiOS18": {
 		Name:           "SafariiOS18",
+		Family:         FamilySafari,
+		OS:             OSiOS,
 		UserAgent:      "Mozilla/5.0 (iPhone; CPU iPhone OS 18_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.1 Mobile/15E148 Safari/604.1",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -635,15 +739,21 @@ var profiles = map[string]*Profile{
 		HeaderOrder: []string{
 			":method", ":scheme", ":path", ":authority", "user-agent", "accept",
 			"accept-language", "accept-encoding", "connection", "upgrade-insecure-requests",
+			"sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "referer", "origin", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":scheme", ":path", ":authority"},
+		CookieCrumbling:   false,
 		SupportedGroups:   []uint16{29, 23, 24, 25},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: safariQUICTransportParameters(),
 		SecHeaders:        map[string]string{},
+		CertCompressionAlgorithms: []uint16{CertCompressionZlib},
 	},
 
 	"ChromeAndroid": {
 		Name:           "ChromeAndroid",
+		Family:         FamilyChromium,
+		OS:             OSAndroid,
 		UserAgent:      "Mozilla/5.0 (Linux; Android 14; SM-G998B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Mobile Safari/537.36",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -686,22 +796,38 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":authority", ":scheme", ":path", "cache-control", "sec-ch-ua",
-			"sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests",
-			"user-agent", "accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
-			"sec-fetch-dest", "accept-encoding", "accept-language",
+			"sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"sec-ch-ua-platform-version", "sec-ch-ua-arch", "sec-ch-ua-bitness", "sec-ch-ua-model",
+			"sec-ch-ua-full-version-list", "upgrade-insecure-requests",
+			"user-agent", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
+			"sec-fetch-dest", "referer", "accept-encoding", "accept-language", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: chromiumQUICTransportParameters(),
 		SecHeaders: map[string]string{
 			"sec-ch-ua":          `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`,
 			"sec-ch-ua-mobile":   "?1",
 			"sec-ch-ua-platform": `"Android"`,
 		},
+		ClientHints: map[string]string{
+			"sec-ch-ua-full-version-list": `"Google Chrome";v="131.0.6778.135", "Chromium";v="131.0.6778.135", "Not_A Brand";v="24.0.0.0"`,
+			"sec-ch-ua-arch":              `""`,
+			"sec-ch-ua-bitness":           `""`,
+			"sec-ch-ua-model":             `"SM-G998B"`,
+			"sec-ch-ua-platform-version":  `"14.0.0"`,
+		},
+		CertCompressionAlgorithms: []uint16{CertCompressionBrotli},
+		ALPSCodepoint:     ALPSCodepointLegacy,
+		ALPSProtocols:     []string{"h2"},
 	},
 
 	"Opera115": {
 		Name:           "Opera115",
+		Family:         FamilyChromium,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 OPR/115.0.0.0",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -744,22 +870,32 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":authority", ":scheme", ":path", "cache-control", "sec-ch-ua",
-			"sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests",
-			"user-agent", "accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
-			"sec-fetch-dest", "accept-encoding", "accept-language",
+			"sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"sec-ch-ua-platform-version", "sec-ch-ua-arch", "sec-ch-ua-bitness", "sec-ch-ua-model",
+			"sec-ch-ua-full-version-list", "upgrade-insecure-requests",
+			"user-agent", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
+			"sec-fetch-dest", "referer", "accept-encoding", "accept-language", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: chromiumQUICTransportParameters(),
 		SecHeaders: map[string]string{
 			"sec-ch-ua":          `"Opera";v="115", "Chromium";v="129", "Not=A?Brand";v="8"`,
 			"sec-ch-ua-mobile":   "?0",
 			"sec-ch-ua-platform": `"Windows"`,
 		},
+		ClientHints: windowsClientHints(`"Opera";v="115.0.5322.77", "Chromium";v="129.0.6668.103", "Not=A?Brand";v="8.0.0.0"`),
+		CertCompressionAlgorithms: []uint16{CertCompressionBrotli},
+		ALPSCodepoint:     ALPSCodepointLegacy,
+		ALPSProtocols:     []string{"h2"},
 	},
 
 	"Brave131": {
 		Name:           "Brave131",
+		Family:         FamilyChromium,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -802,22 +938,32 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":authority", ":scheme", ":path", "cache-control", "sec-ch-ua",
-			"sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests",
-			"user-agent", "accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
-			"sec-fetch-dest", "accept-encoding", "accept-language",
+			"sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"sec-ch-ua-platform-version", "sec-ch-ua-arch", "sec-ch-ua-bitness", "sec-ch-ua-model",
+			"sec-ch-ua-full-version-list", "upgrade-insecure-requests",
+			"user-agent", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user",
+			"sec-fetch-dest", "referer", "accept-encoding", "accept-language", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{29, 23, 24},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: chromiumQUICTransportParameters(),
 		SecHeaders: map[string]string{
 			"sec-ch-ua":          `"Brave";v="131", "Chromium";v="131", "Not_A Brand";v="24"`,
 			"sec-ch-ua-mobile":   "?0",
 			"sec-ch-ua-platform": `"Windows"`,
 		},
+		ClientHints: windowsClientHints(`"Brave";v="131.0.0.0", "Chromium";v="131.0.0.0", "Not_A Brand";v="24.0.0.0"`),
+		CertCompressionAlgorithms: []uint16{CertCompressionBrotli},
+		ALPSCodepoint:     ALPSCodepointLegacy,
+		ALPSProtocols:     []string{"h2"},
 	},
 
 	"Brave138": {
 		Name:           "Brave138",
+		Family:         FamilyChromium,
+		OS:             OSWindows,
 		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
 		AcceptLanguage: "en-US,en;q=0.9",
 		AcceptEncoding: "gzip, deflate, br",
@@ -842,6 +988,7 @@ var profiles = map[string]*Profile{
 			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
 		},
 		CurvePreferences: []tls.CurveID{
+			tls.X25519MLKEM768,
 			tls.X25519,
 			tls.CurveP256,
 			tls.CurveP384,
@@ -860,27 +1007,97 @@ var profiles = map[string]*Profile{
 		},
 		HeaderOrder: []string{
 			":method", ":authority", ":scheme", ":path", "sec-ch-ua",
-			"sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests",
-			"user-agent", "accept", "sec-gpc", "accept-language", "sec-fetch-site", 
-			"sec-fetch-mode", "sec-fetch-user", "sec-fetch-dest", "accept-encoding", 
+			"sec-ch-ua-mobile", "sec-ch-ua-platform",
+			"sec-ch-ua-platform-version", "sec-ch-ua-arch", "sec-ch-ua-bitness", "sec-ch-ua-model",
+			"sec-ch-ua-full-version-list", "upgrade-insecure-requests",
+			"user-agent", "accept", "sec-gpc", "accept-language", "origin", "sec-fetch-site", 
+			"sec-fetch-mode", "sec-fetch-user", "sec-fetch-dest", "referer", "accept-encoding", 
 			"cache-control", "priority",
 		},
 		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+		CookieCrumbling:   true,
 		SupportedGroups:   []uint16{4588, 29, 23, 24},
 		ALPNProtocols:     []string{"h2", "http/1.1"},
+		QUICTransportParameters: chromiumQUICTransportParameters(),
 		SecHeaders: map[string]string{
 			"sec-ch-ua":          `"Brave";v="138", "Chromium";v="138", "Not_A Brand";v="24"`,
 			"sec-ch-ua-mobile":   "?0",
 			"sec-ch-ua-platform": `"Windows"`,
 		},
+		ClientHints: windowsClientHints(`"Brave";v="138.0.0.0", "Chromium";v="138.0.0.0", "Not_A Brand";v="24.0.0.0"`),
+		CertCompressionAlgorithms: []uint16{CertCompressionBrotli},
+		ALPSCodepoint:     ALPSCodepoint,
+		ALPSProtocols:     []string{"h2"},
 	},
 }
 
-func Get(name string) (*Profile, error) {
+func chromiumQUICTransportParameters() map[string]uint64 {
+	return map[string]uint64{
+		"initial_max_data":                   15728640,
+		"initial_max_stream_data_bidi_local": 6291456,
+		"initial_max_streams_bidi":           100,
+		"initial_max_streams_uni":            103,
+		"max_idle_timeout":                   30000,
+	}
+}
+
+func firefoxQUICTransportParameters() map[string]uint64 {
+	return map[string]uint64{
+		"initial_max_data":                   25165824,
+		"initial_max_stream_data_bidi_local": 12582912,
+		"initial_max_streams_bidi":           16,
+		"initial_max_streams_uni":            16,
+		"max_idle_timeout":                   30000,
+	}
+}
+
+func safariQUICTransportParameters() map[string]uint64 {
+	return map[string]uint64{
+		"initial_max_data":                   2097152,
+		"initial_max_stream_data_bidi_local": 2097152,
+		"initial_max_streams_bidi":           100,
+		"initial_max_streams_uni":            100,
+		"max_idle_timeout":                   30000,
+	}
+}
+
+func windowsClientHints(fullVersionList string) map[string]string {
+	return map[string]string{
+		"sec-ch-ua-full-version-list": fullVersionList,
+		"sec-ch-ua-arch":              `"x86"`,
+		"sec-ch-ua-bitness":           `"64"`,
+		"sec-ch-ua-model":             `""`,
+		"sec-ch-ua-platform-version":  `"10.0.0"`,
+	}
+}
+
+func Get(name string, opts ...Option) (*Profile, error) {
 	profile, exists := profiles[name]
 	if !exists {
 		return nil, fmt.Errorf("unknown profile: %s", name)
 	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.os != "" {
+		var err error
+		if profile, err = withOS(profile, o.os); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(o.locales) > 0 {
+		acceptLanguage, err := AcceptLanguage(profile.Family, o.locales)
+		if err != nil {
+			return nil, err
+		}
+		profile = profile.clone()
+		profile.AcceptLanguage = acceptLanguage
+	}
+
 	return profile, nil
 }
 
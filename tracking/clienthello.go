@@ -0,0 +1,354 @@
+package tracking
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+type KeyShareEntry struct {
+	Group  uint16 `json:"group"`
+	Length int    `json:"length"`
+}
+
+type ClientHello struct {
+	Random              []byte
+	SessionID           []byte
+	CipherSuites        []uint16
+	Extensions          []uint16
+	SupportedGroups     []uint16
+	SignatureAlgorithms []uint16
+	SupportedVersions   []uint16
+	ALPNProtocols       []string
+	KeyShares           []KeyShareEntry
+	CertCompression     []uint16
+	ALPSCodepoint       uint16
+	ALPSProtocols       []string
+}
+
+type helloRecorder struct {
+	net.Conn
+	mu   sync.Mutex
+	buf  []byte
+	done bool
+
+	server     []byte
+	handshake  []byte
+	serverDone bool
+	group      uint16
+}
+
+func newHelloRecorder(conn net.Conn) *helloRecorder {
+	return &helloRecorder{Conn: conn}
+}
+
+func (r *helloRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	if !r.done {
+		r.buf = append(r.buf, p...)
+		if len(r.buf) >= 5 && len(r.buf) >= 5+int(binary.BigEndian.Uint16(r.buf[3:5])) {
+			r.done = true
+		}
+	}
+	r.mu.Unlock()
+	return r.Conn.Write(p)
+}
+
+func (r *helloRecorder) Read(p []byte) (int, error) {
+	n, err := r.Conn.Read(p)
+	if n > 0 {
+		r.mu.Lock()
+		if !r.serverDone {
+			r.server = append(r.server, p[:n]...)
+			r.scanServer()
+		}
+		r.mu.Unlock()
+	}
+	return n, err
+}
+
+func (r *helloRecorder) scanServer() {
+	for !r.serverDone && len(r.server) >= 5 {
+		length := 5 + int(binary.BigEndian.Uint16(r.server[3:5]))
+		if len(r.server) < length {
+			return
+		}
+		recordType := r.server[0]
+		payload := r.server[5:length]
+		r.server = r.server[length:]
+
+		switch recordType {
+		case 22:
+			r.handshake = append(r.handshake, payload...)
+			r.scanHandshake()
+		case 20:
+		default:
+			r.serverDone = true
+		}
+	}
+	if r.serverDone {
+		r.server = nil
+		r.handshake = nil
+	}
+}
+
+func (r *helloRecorder) scanHandshake() {
+	for !r.serverDone && len(r.handshake) >= 4 {
+		length := 4 + (int(r.handshake[1])<<16 | int(r.handshake[2])<<8 | int(r.handshake[3]))
+		if len(r.handshake) < length {
+			return
+		}
+		msgType := r.handshake[0]
+		body := byteReader(r.handshake[4:length])
+		r.handshake = r.handshake[length:]
+
+		switch msgType {
+		case 2:
+			if group, tls13 := parseServerHelloGroup(body); tls13 {
+				r.group = group
+				r.serverDone = true
+			}
+		case 12:
+			if curveType, ok := body.uint8(); ok && curveType == 3 {
+				r.group, _ = body.uint16()
+			}
+			r.serverDone = true
+		case 14:
+			r.serverDone = true
+		}
+	}
+}
+
+func parseServerHelloGroup(s byteReader) (uint16, bool) {
+	if _, ok := s.take(2 + 32); !ok {
+		return 0, false
+	}
+	if _, ok := s.bytes8(); !ok {
+		return 0, false
+	}
+	if _, ok := s.take(2 + 1); !ok {
+		return 0, false
+	}
+	exts, ok := s.bytes16()
+	if !ok {
+		return 0, false
+	}
+
+	var group uint16
+	var tls13 bool
+	for len(exts) > 0 {
+		extType, ok := exts.uint16()
+		if !ok {
+			break
+		}
+		data, ok := exts.bytes16()
+		if !ok {
+			break
+		}
+		switch extType {
+		case 43:
+			version, _ := data.uint16()
+			tls13 = version == tls.VersionTLS13
+		case 51:
+			group, _ = data.uint16()
+		}
+	}
+	return group, tls13
+}
+
+func (r *helloRecorder) NegotiatedGroup() uint16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.group
+}
+
+func (r *helloRecorder) ClientHello() (*ClientHello, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.done {
+		return nil, false
+	}
+	return ParseClientHello(r.buf)
+}
+
+func ParseClientHello(record []byte) (*ClientHello, bool) {
+	if len(record) < 5 || record[0] != 22 {
+		return nil, false
+	}
+	s := byteReader(record[5:])
+
+	msgType, ok := s.uint8()
+	if !ok || msgType != 1 {
+		return nil, false
+	}
+	body, ok := s.bytes24()
+	if !ok {
+		return nil, false
+	}
+	s = byteReader(body)
+
+	hello := &ClientHello{}
+	if _, ok := s.uint16(); !ok {
+		return nil, false
+	}
+	if hello.Random, ok = s.take(32); !ok {
+		return nil, false
+	}
+	if hello.SessionID, ok = s.bytes8(); !ok {
+		return nil, false
+	}
+	suites, ok := s.bytes16()
+	if !ok {
+		return nil, false
+	}
+	hello.CipherSuites = uint16List(suites)
+	if _, ok := s.bytes8(); !ok {
+		return nil, false
+	}
+	if len(s) == 0 {
+		return hello, true
+	}
+	exts, ok := s.bytes16()
+	if !ok {
+		return nil, false
+	}
+
+	for len(exts) > 0 {
+		extType, ok := exts.uint16()
+		if !ok {
+			return nil, false
+		}
+		data, ok := exts.bytes16()
+		if !ok {
+			return nil, false
+		}
+		hello.Extensions = append(hello.Extensions, extType)
+		hello.parseExtension(extType, data)
+	}
+
+	return hello, true
+}
+
+func (h *ClientHello) parseExtension(extType uint16, data byteReader) {
+	switch extType {
+	case 10:
+		if list, ok := data.bytes16(); ok {
+			h.SupportedGroups = uint16List(list)
+		}
+	case 13:
+		if list, ok := data.bytes16(); ok {
+			h.SignatureAlgorithms = uint16List(list)
+		}
+	case 16:
+		list, ok := data.bytes16()
+		if !ok {
+			return
+		}
+		for len(list) > 0 {
+			proto, ok := list.bytes8()
+			if !ok {
+				return
+			}
+			h.ALPNProtocols = append(h.ALPNProtocols, string(proto))
+		}
+	case 27:
+		if list, ok := data.bytes8(); ok {
+			h.CertCompression = uint16List(list)
+		}
+	case 17513, 17613:
+		h.ALPSCodepoint = extType
+		list, ok := data.bytes16()
+		if !ok {
+			return
+		}
+		for len(list) > 0 {
+			proto, ok := list.bytes8()
+			if !ok {
+				return
+			}
+			h.ALPSProtocols = append(h.ALPSProtocols, string(proto))
+		}
+	case 43:
+		if list, ok := data.bytes8(); ok {
+			h.SupportedVersions = uint16List(list)
+		}
+	case 51:
+		list, ok := data.bytes16()
+		if !ok {
+			return
+		}
+		for len(list) > 0 {
+			group, ok := list.uint16()
+			if !ok {
+				return
+			}
+			share, ok := list.bytes16()
+			if !ok {
+				return
+			}
+			h.KeyShares = append(h.KeyShares, KeyShareEntry{Group: group, Length: len(share)})
+		}
+	}
+}
+
+type byteReader []byte
+
+func (b *byteReader) take(n int) ([]byte, bool) {
+	if len(*b) < n {
+		return nil, false
+	}
+	out := (*b)[:n]
+	*b = (*b)[n:]
+	return out, true
+}
+
+func (b *byteReader) uint8() (uint8, bool) {
+	v, ok := b.take(1)
+	if !ok {
+		return 0, false
+	}
+	return v[0], true
+}
+
+func (b *byteReader) uint16() (uint16, bool) {
+	v, ok := b.take(2)
+	if !ok {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(v), true
+}
+
+func (b *byteReader) bytes8() (byteReader, bool) {
+	n, ok := b.uint8()
+	if !ok {
+		return nil, false
+	}
+	v, ok := b.take(int(n))
+	return v, ok
+}
+
+func (b *byteReader) bytes16() (byteReader, bool) {
+	n, ok := b.uint16()
+	if !ok {
+		return nil, false
+	}
+	v, ok := b.take(int(n))
+	return v, ok
+}
+
+func (b *byteReader) bytes24() (byteReader, bool) {
+	v, ok := b.take(3)
+	if !ok {
+		return nil, false
+	}
+	return b.take(int(v[0])<<16 | int(v[1])<<8 | int(v[2]))
+}
+
+func uint16List(b []byte) []uint16 {
+	out := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		out = append(out, binary.BigEndian.Uint16(b[i:]))
+	}
+	return out
+}
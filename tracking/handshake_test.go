@@ -0,0 +1,431 @@
+package tracking
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+type testServerConfig struct {
+	certificate []byte
+	key         *ecdsa.PrivateKey
+	compression uint16
+	compress    func([]byte) []byte
+	protocol    string
+	alps        uint16
+	settings    []byte
+}
+
+type testServerResult struct {
+	err        error
+	clientALPS []byte
+}
+
+func newTestCertificate(t *testing.T) ([]byte, *ecdsa.PrivateKey, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example.com"},
+		DNSNames:              []string{"example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return der, key, roots
+}
+
+func startTestServer(t *testing.T, config testServerConfig) (string, <-chan testServerResult) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	results := make(chan testServerResult, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			results <- testServerResult{err: err}
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		clientALPS, err := serveTLS13(conn, config)
+		results <- testServerResult{err: err, clientALPS: clientALPS}
+	}()
+	return listener.Addr().String(), results
+}
+
+func serveTLS13(conn net.Conn, config testServerConfig) ([]byte, error) {
+	recordType, clientHello, err := readRecord(conn)
+	if err != nil {
+		return nil, err
+	}
+	if recordType != 22 || len(clientHello) < 4 || clientHello[0] != 1 {
+		return nil, errors.New("expected a ClientHello record")
+	}
+	sessionID, clientShare, err := parseTestClientHello(clientHello[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	transcript := sha256.New()
+	transcript.Write(clientHello)
+
+	serverKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	peerKey, err := ecdh.X25519().NewPublicKey(clientShare)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := serverKey.ECDH(peerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	serverHello := handshakeMessage(2, func(b *cryptobyte.Builder) {
+		random := make([]byte, 32)
+		rand.Read(random)
+		b.AddUint16(tls.VersionTLS12)
+		b.AddBytes(random)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sessionID) })
+		b.AddUint16(tls.TLS_AES_128_GCM_SHA256)
+		b.AddUint8(0)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(43)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint16(tls.VersionTLS13) })
+			b.AddUint16(51)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(uint16(tls.X25519))
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(serverKey.PublicKey().Bytes()) })
+			})
+		})
+	})
+	transcript.Write(serverHello)
+	if _, err := conn.Write(append([]byte{22, 3, 3, byte(len(serverHello) >> 8), byte(len(serverHello))}, serverHello...)); err != nil {
+		return nil, err
+	}
+
+	early, _ := hkdf.Extract(sha256.New, make([]byte, 32), nil)
+	empty := sha256.Sum256(nil)
+	handshakeSecret, _ := hkdf.Extract(sha256.New, shared, expandLabel(early, "derived", empty[:], 32))
+	clientSecret := expandLabel(handshakeSecret, "c hs traffic", transcript.Sum(nil), 32)
+	serverSecret := expandLabel(handshakeSecret, "s hs traffic", transcript.Sum(nil), 32)
+
+	var flight []byte
+	add := func(msg []byte) {
+		transcript.Write(msg)
+		flight = append(flight, msg...)
+	}
+
+	add(handshakeMessage(8, func(b *cryptobyte.Builder) {
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			if config.protocol != "" {
+				b.AddUint16(16)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(config.protocol)) })
+					})
+				})
+			}
+			if config.alps != 0 {
+				b.AddUint16(config.alps)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(config.settings) })
+			}
+		})
+	}))
+
+	certificate := handshakeBody(func(b *cryptobyte.Builder) {
+		b.AddUint8(0)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(config.certificate) })
+			b.AddUint16(0)
+		})
+	})
+	if config.compress != nil {
+		add(handshakeMessage(25, func(b *cryptobyte.Builder) {
+			b.AddUint16(config.compression)
+			b.AddUint24(uint32(len(certificate)))
+			b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(config.compress(certificate)) })
+		}))
+	} else {
+		add(handshakeMessage(11, func(b *cryptobyte.Builder) { b.AddBytes(certificate) }))
+	}
+
+	signed := append(bytes.Repeat([]byte{0x20}, 64), "TLS 1.3, server CertificateVerify\x00"...)
+	digest := sha256.Sum256(append(signed, transcript.Sum(nil)...))
+	signature, err := ecdsa.SignASN1(rand.Reader, config.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	add(handshakeMessage(15, func(b *cryptobyte.Builder) {
+		b.AddUint16(uint16(tls.ECDSAWithP256AndSHA256))
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(signature) })
+	}))
+	add(handshakeMessage(20, func(b *cryptobyte.Builder) {
+		b.AddBytes(finishedMAC(serverSecret, transcript))
+	}))
+
+	serverCipher := newRecordCipher(serverSecret)
+	if _, err := conn.Write(serverCipher.seal(22, flight)); err != nil {
+		return nil, err
+	}
+
+	clientCipher := newRecordCipher(clientSecret)
+	var clientALPS []byte
+	for {
+		recordType, payload, err := readRecord(conn)
+		if err != nil {
+			return nil, err
+		}
+		switch recordType {
+		case 20:
+			continue
+		case 21:
+			return nil, fmt.Errorf("client sent alert %d", payload[len(payload)-1])
+		}
+
+		contentType, plaintext, err := clientCipher.open(payload)
+		if err != nil {
+			return nil, err
+		}
+		if contentType == 21 {
+			return nil, fmt.Errorf("client sent alert %d", plaintext[len(plaintext)-1])
+		}
+		if contentType != 22 {
+			return nil, errors.New("expected a client handshake record")
+		}
+
+		messages := byteReader(plaintext)
+		for len(messages) > 0 {
+			raw := []byte(messages)
+			msgType, _ := messages.uint8()
+			body, ok := messages.bytes24()
+			if !ok {
+				return nil, errors.New("truncated client handshake message")
+			}
+			switch msgType {
+			case 8:
+				clientALPS = parseTestALPS(body, config.alps)
+				transcript.Write(raw[:4+len(body)])
+			case 20:
+				if !hmac.Equal(body, finishedMAC(clientSecret, transcript)) {
+					return nil, errors.New("client Finished does not match the transcript")
+				}
+				return clientALPS, nil
+			default:
+				return nil, fmt.Errorf("unexpected client handshake message %d", msgType)
+			}
+		}
+	}
+}
+
+func parseTestALPS(body byteReader, codepoint uint16) []byte {
+	exts, _ := body.bytes16()
+	for len(exts) > 0 {
+		extType, _ := exts.uint16()
+		data, ok := exts.bytes16()
+		if !ok {
+			break
+		}
+		if extType == codepoint {
+			return append([]byte{}, data...)
+		}
+	}
+	return nil
+}
+
+func parseTestClientHello(body byteReader) ([]byte, []byte, error) {
+	if _, ok := body.take(2 + 32); !ok {
+		return nil, nil, errors.New("short ClientHello")
+	}
+	sessionID, _ := body.bytes8()
+	body.bytes16()
+	body.bytes8()
+	exts, ok := body.bytes16()
+	if !ok {
+		return nil, nil, errors.New("ClientHello has no extensions")
+	}
+	for len(exts) > 0 {
+		extType, _ := exts.uint16()
+		data, ok := exts.bytes16()
+		if !ok {
+			break
+		}
+		if extType != 51 {
+			continue
+		}
+		shares, _ := data.bytes16()
+		for len(shares) > 0 {
+			group, _ := shares.uint16()
+			share, ok := shares.bytes16()
+			if !ok {
+				break
+			}
+			if group == uint16(tls.X25519) {
+				return sessionID, share, nil
+			}
+		}
+	}
+	return nil, nil, errors.New("ClientHello has no X25519 key share")
+}
+
+func readRecord(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[3:]))
+	_, err := io.ReadFull(r, payload)
+	return header[0], payload, err
+}
+
+func handshakeBody(build func(b *cryptobyte.Builder)) []byte {
+	var b cryptobyte.Builder
+	build(&b)
+	return b.BytesOrPanic()
+}
+
+func handshakeMessage(msgType uint8, build func(b *cryptobyte.Builder)) []byte {
+	var b cryptobyte.Builder
+	b.AddUint8(msgType)
+	b.AddUint24LengthPrefixed(build)
+	return b.BytesOrPanic()
+}
+
+func expandLabel(secret []byte, label string, context []byte, length int) []byte {
+	var info cryptobyte.Builder
+	info.AddUint16(uint16(length))
+	info.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("tls13 " + label)) })
+	info.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(context) })
+	out, err := hkdf.Expand(sha256.New, secret, string(info.BytesOrPanic()), length)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func finishedMAC(secret []byte, transcript hash.Hash) []byte {
+	mac := hmac.New(sha256.New, expandLabel(secret, "finished", nil, 32))
+	mac.Write(transcript.Sum(nil))
+	return mac.Sum(nil)
+}
+
+type recordCipher struct {
+	aead cipher.AEAD
+	iv   []byte
+	seq  uint64
+}
+
+func newRecordCipher(secret []byte) *recordCipher {
+	block, err := aes.NewCipher(expandLabel(secret, "key", nil, 16))
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &recordCipher{aead: aead, iv: expandLabel(secret, "iv", nil, 12)}
+}
+
+func (c *recordCipher) nonce() []byte {
+	nonce := append([]byte(nil), c.iv...)
+	for i := 0; i < 8; i++ {
+		nonce[4+i] ^= byte(c.seq >> (56 - 8*i))
+	}
+	c.seq++
+	return nonce
+}
+
+func (c *recordCipher) seal(contentType byte, data []byte) []byte {
+	plaintext := append(append([]byte(nil), data...), contentType)
+	header := []byte{23, 3, 3, 0, 0}
+	binary.BigEndian.PutUint16(header[3:], uint16(len(plaintext)+c.aead.Overhead()))
+	return append(header, c.aead.Seal(nil, c.nonce(), plaintext, header)...)
+}
+
+func (c *recordCipher) open(payload []byte) (byte, []byte, error) {
+	header := []byte{23, 3, 3, 0, 0}
+	binary.BigEndian.PutUint16(header[3:], uint16(len(payload)))
+	plaintext, err := c.aead.Open(nil, c.nonce(), payload, header)
+	if err != nil {
+		return 0, nil, err
+	}
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 {
+		return 0, nil, errors.New("empty inner plaintext")
+	}
+	return plaintext[len(plaintext)-1], plaintext[:len(plaintext)-1], nil
+}
+
+func dialTestServer(t *testing.T, dialer *TrackedDialer, addr string, roots *x509.CertPool, extensions ...utls.TLSExtension) (net.Conn, *ConnectionDetails, error) {
+	t.Helper()
+	dialer.SetClientHelloFunc(func(config *tls.Config) *utls.ClientHelloSpec {
+		return &utls.ClientHelloSpec{
+			TLSVersMin:         tls.VersionTLS13,
+			TLSVersMax:         tls.VersionTLS13,
+			CipherSuites:       []uint16{tls.TLS_AES_128_GCM_SHA256},
+			CompressionMethods: []uint8{0},
+			Extensions: append([]utls.TLSExtension{
+				&utls.SNIExtension{},
+				&utls.SupportedCurvesExtension{Curves: []utls.CurveID{utls.X25519}},
+				&utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{utls.ECDSAWithP256AndSHA256}},
+				&utls.SupportedVersionsExtension{Versions: []uint16{tls.VersionTLS13}},
+				&utls.KeyShareExtension{KeyShares: []utls.KeyShare{{Group: utls.X25519}}},
+			}, extensions...),
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialer.DialTLSContext(ctx, "tcp", addr, &tls.Config{ServerName: "example.com", RootCAs: roots})
+	if err != nil {
+		return nil, nil, err
+	}
+	details, _ := dialer.tracker.GetConnection(addr)
+	return conn, details, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"golang.org/x/net/http2/hpack"
 	"github.com/rip-zoyo/orbit-tls/fingerprint"
+	"github.com/rip-zoyo/orbit-tls/profiles"
 )
 
 type HTTP2Tracker struct {
@@ -156,6 +157,28 @@ func CreateHTTP2FrameForProfile(profileName string) []fingerprint.Frame {
 	}
 }
 
+func CreateHTTP2FramesForProfile(profile *profiles.Profile) []fingerprint.Frame {
+	var frames []fingerprint.Frame
+	switch profile.Family {
+	case profiles.FamilyFirefox:
+		frames = CreateDefaultFirefoxFrames()
+	case profiles.FamilySafari:
+		frames = CreateDefaultSafariFrames()
+	default:
+		frames = CreateDefaultChromeFrames()
+	}
+
+	if len(profile.HTTP2Settings) > 0 {
+		settings := make(map[string]uint32, len(profile.HTTP2Settings))
+		for key, value := range profile.HTTP2Settings {
+			settings[key] = value
+		}
+		frames[0].Settings = settings
+		frames[0].Length = uint32(6 * len(settings))
+	}
+	return frames
+}
+
 func EstimateHeadersSize(req *http.Request) int {
 	size := 0
 	size += len(req.Method) + len(req.URL.Path) + len(req.Proto)
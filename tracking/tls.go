@@ -1,6 +1,8 @@
 package tracking
 
 import (
+	"container/list"
+	"context"
 	"crypto/tls"
 	"encoding/hex"
 	"fmt"
@@ -9,12 +11,32 @@ import (
 	"sync"
 	"time"
 
+	utls "github.com/refraction-networking/utls"
+
 	"github.com/rip-zoyo/orbit-tls/fingerprint"
 )
 
 type TLSTracker struct {
 	mu          sync.RWMutex
 	connections map[string]*ConnectionDetails
+	order       *list.List
+	positions   map[string]*list.Element
+	maxEntries  int
+	maxAge      time.Duration
+}
+
+type trackedEntry struct {
+	addr   string
+	stored time.Time
+}
+
+const (
+	DefaultMaxTrackedConnections = 1024
+	DefaultTrackedConnectionAge  = time.Hour
+)
+
+type DetailsReceiver interface {
+	ReceiveConnectionDetails(details *ConnectionDetails)
 }
 
 type ConnectionDetails struct {
@@ -30,17 +52,64 @@ type ConnectionDetails struct {
 	ALPNProtocols        []string  `json:"alpn_protocols"`
 	ServerName           string    `json:"server_name"`
 	KeyShare             []byte    `json:"key_share"`
+	KeyShares            []KeyShareEntry `json:"key_shares"`
+	NegotiatedGroup      uint16    `json:"negotiated_group"`
+	ECHOffered           bool      `json:"ech_offered"`
+	ECHAccepted          bool      `json:"ech_accepted"`
+	ECHGREASE            bool      `json:"ech_grease"`
+	PSKOffered           bool      `json:"psk_offered"`
+	Resumed              bool      `json:"resumed"`
+	EarlyData            bool      `json:"early_data"`
+	CertCompressionAlgorithms []uint16 `json:"cert_compression_algorithms"`
+	ALPSCodepoint        uint16    `json:"alps_codepoint"`
+	ALPSProtocols        []string  `json:"alps_protocols"`
+	ALPSNegotiated       bool      `json:"alps_negotiated"`
+	PeerApplicationSettings []byte `json:"peer_application_settings,omitempty"`
+	Protocol             string    `json:"protocol"`
+	Cleartext            bool      `json:"cleartext"`
+	QUICTransportParameters map[string]uint64 `json:"quic_transport_parameters,omitempty"`
 	PeerCertificates     [][]byte  `json:"peer_certificates"`
 	HandshakeComplete    bool      `json:"handshake_complete"`
 	ConnectedAt          time.Time `json:"connected_at"`
+	RemoteAddr           string    `json:"remote_addr,omitempty"`
+	Resolution           *Resolution `json:"resolution,omitempty"`
 	HTTP2Settings        map[string]uint32 `json:"http2_settings"`
 	HTTP2Frames          []fingerprint.Frame `json:"http2_frames"`
 	HTTP2WindowUpdate    uint32           `json:"http2_window_update"`
 	HTTP2Priority        *fingerprint.HeaderPriority `json:"http2_priority"`
 }
 
+type Resolution struct {
+	Host   string   `json:"host"`
+	Source string   `json:"source"`
+	Addrs  []string `json:"addrs"`
+}
+
+type ResolvedConn interface {
+	Resolution() *Resolution
+}
+
+type HandshakeFailure struct {
+	Addr    string
+	Details *ConnectionDetails
+	Err     error
+}
+
+func (e *HandshakeFailure) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HandshakeFailure) Unwrap() error {
+	return e.Err
+}
+
 type TrackedDialer struct {
 	dialer  *net.Dialer
+	dial    func(ctx context.Context, network, addr string) (net.Conn, error)
+	verify  func(state tls.ConnectionState, details *ConnectionDetails) error
+	hello   ClientHelloFunc
+	sessions utls.ClientSessionCache
+	applicationSettings map[string][]byte
 	tracker *TLSTracker
 }
 
@@ -49,41 +118,154 @@ var GlobalTracker = NewTLSTracker()
 func NewTLSTracker() *TLSTracker {
 	return &TLSTracker{
 		connections: make(map[string]*ConnectionDetails),
+		order:       list.New(),
+		positions:   make(map[string]*list.Element),
+		maxEntries:  DefaultMaxTrackedConnections,
+		maxAge:      DefaultTrackedConnectionAge,
 	}
 }
 
 func NewTrackedDialer() *TrackedDialer {
-	return &TrackedDialer{
+	td := &TrackedDialer{
 		dialer: &net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
 		},
 		tracker: GlobalTracker,
 	}
+	td.dial = td.dialer.DialContext
+	return td
+}
+
+func (td *TrackedDialer) SetDialFunc(dial func(ctx context.Context, network, addr string) (net.Conn, error)) {
+	if dial == nil {
+		dial = td.dialer.DialContext
+	}
+	td.dial = dial
+}
+
+func (td *TrackedDialer) SetTracker(tracker *TLSTracker) {
+	td.tracker = tracker
+}
+
+func (td *TrackedDialer) SetVerifyFunc(verify func(state tls.ConnectionState, details *ConnectionDetails) error) {
+	td.verify = verify
+}
+
+func (td *TrackedDialer) SetClientHelloFunc(hello ClientHelloFunc) {
+	td.hello = hello
+}
+
+func (td *TrackedDialer) SetSessionCache(capacity int) {
+	if capacity <= 0 {
+		td.sessions = nil
+		return
+	}
+	td.sessions = utls.NewLRUClientSessionCache(capacity)
+}
+
+func (td *TrackedDialer) SetApplicationSettings(settings map[string][]byte) {
+	if len(settings) == 0 {
+		td.applicationSettings = nil
+		return
+	}
+	td.applicationSettings = make(map[string][]byte, len(settings)+1)
+	for protocol, value := range settings {
+		td.applicationSettings[protocol] = value
+		if len(settings) == 1 {
+			td.applicationSettings[""] = value
+		}
+	}
 }
 
 func (td *TrackedDialer) DialTLS(network, addr string, config *tls.Config) (net.Conn, error) {
+	return td.DialTLSContext(context.Background(), network, addr, config)
+}
+
+func (td *TrackedDialer) DialTLSContext(ctx context.Context, network, addr string, config *tls.Config) (net.Conn, error) {
 	details := &ConnectionDetails{
 		ConnectedAt: time.Now(),
 		ServerName:  config.ServerName,
 	}
 
-	trackedConfig := td.createTrackedTLSConfig(config, details)
-	
-	conn, err := tls.Dial(network, addr, trackedConfig)
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		config = config.Clone()
+		config.ServerName = host
+		details.ServerName = host
+	}
+
+	rawConn, err := td.dial(ctx, network, addr)
 	if err != nil {
 		return nil, err
 	}
+	recordRemote(details, rawConn)
+
+	recorder := newHelloRecorder(rawConn)
+	trackedConfig := td.createTrackedTLSConfig(config, details, recorder)
+	var conn *utls.UConn
+	if td.hello != nil {
+		conn = utls.UClient(recorder, trackedConfig, utls.HelloCustom)
+		err = conn.ApplyPreset(td.hello(config))
+	} else {
+		conn = utls.UClient(recorder, trackedConfig, utls.HelloGolang)
+	}
+	if err == nil {
+		err = conn.HandshakeContext(ctx)
+	}
+
+	if hello, ok := recorder.ClientHello(); ok {
+		details.ClientRandom = hello.Random
+		details.SessionID = hello.SessionID
+		details.Extensions = hello.Extensions
+		details.SupportedGroups = hello.SupportedGroups
+		details.SignatureAlgorithms = hello.SignatureAlgorithms
+		details.SupportedVersions = hello.SupportedVersions
+		details.ALPNProtocols = hello.ALPNProtocols
+		details.KeyShares = hello.KeyShares
+		details.CertCompressionAlgorithms = hello.CertCompression
+		details.ALPSCodepoint = hello.ALPSCodepoint
+		details.ALPSProtocols = hello.ALPSProtocols
+		for _, ext := range hello.Extensions {
+			switch ext {
+			case 41:
+				details.PSKOffered = true
+			case 65037:
+				details.ECHOffered = true
+				details.ECHGREASE = len(config.EncryptedClientHelloConfigList) == 0
+			}
+		}
+	}
+
+	if err != nil {
+		rawConn.Close()
+		return nil, &HandshakeFailure{Addr: addr, Details: details, Err: convertError(err)}
+	}
+
+	if settings := conn.ConnectionState().PeerApplicationSettings; settings != nil {
+		details.ALPSNegotiated = true
+		details.PeerApplicationSettings = settings
+	}
 
-	td.updateConnectionDetails(addr, conn, details)
+	tracked := &trackedConn{UConn: conn, group: recorder.NegotiatedGroup()}
+	td.storeConnectionState(addr, tracked.ConnectionState(), details)
+	if receiver, ok := rawConn.(DetailsReceiver); ok {
+		receiver.ReceiveConnectionDetails(details)
+	}
 	
-	return conn, nil
+	return tracked, nil
 }
 
-func (td *TrackedDialer) createTrackedTLSConfig(original *tls.Config, details *ConnectionDetails) *tls.Config {
-	config := original.Clone()
+func (td *TrackedDialer) createTrackedTLSConfig(original *tls.Config, details *ConnectionDetails, recorder *helloRecorder) *utls.Config {
+	config := utlsConfig(original)
+	config.ClientSessionCache = td.sessions
+	config.ApplicationSettings = td.applicationSettings
 	
-	config.VerifyConnection = func(cs tls.ConnectionState) error {
+	config.VerifyConnection = func(ucs utls.ConnectionState) error {
+		cs := connectionState(ucs, recorder.NegotiatedGroup())
 		details.TLSVersion = cs.Version
 		details.CipherSuite = cs.CipherSuite
 		
@@ -96,6 +278,12 @@ func (td *TrackedDialer) createTrackedTLSConfig(original *tls.Config, details *C
 		
 		details.HandshakeComplete = true
 		
+		if td.verify != nil {
+			if err := td.verify(cs, details); err != nil {
+				return err
+			}
+		}
+		
 		if original.VerifyConnection != nil {
 			return original.VerifyConnection(cs)
 		}
@@ -106,12 +294,65 @@ func (td *TrackedDialer) createTrackedTLSConfig(original *tls.Config, details *C
 	return config
 }
 
-func (td *TrackedDialer) updateConnectionDetails(addr string, conn *tls.Conn, details *ConnectionDetails) {
-	state := conn.ConnectionState()
-	
+func (td *TrackedDialer) DialCleartext(ctx context.Context, network, addr, protocol string) (net.Conn, error) {
+	conn, err := td.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	details := &ConnectionDetails{
+		ConnectedAt: time.Now(),
+		ServerName:  host,
+		Protocol:    protocol,
+		Cleartext:   true,
+	}
+	recordRemote(details, conn)
+	td.tracker.StoreConnection(addr, details)
+	if receiver, ok := conn.(DetailsReceiver); ok {
+		receiver.ReceiveConnectionDetails(details)
+	}
+	return conn, nil
+}
+
+func recordRemote(details *ConnectionDetails, conn net.Conn) {
+	if remote := conn.RemoteAddr(); remote != nil {
+		details.RemoteAddr = remote.String()
+	}
+	if resolved, ok := conn.(ResolvedConn); ok {
+		details.Resolution = resolved.Resolution()
+	}
+}
+
+func (td *TrackedDialer) RecordQUICConnection(addr string, conn net.Conn, state tls.ConnectionState, earlyData bool, transportParameters map[string]uint64) *ConnectionDetails {
+	details := &ConnectionDetails{
+		ConnectedAt:             time.Now(),
+		ServerName:              state.ServerName,
+		ALPNProtocols:           []string{"h3"},
+		EarlyData:               earlyData,
+		QUICTransportParameters: transportParameters,
+	}
+	recordRemote(details, conn)
+
+	td.storeConnectionState(addr, state, details)
+	if receiver, ok := conn.(DetailsReceiver); ok {
+		receiver.ReceiveConnectionDetails(details)
+	}
+	return details
+}
+
+func (td *TrackedDialer) storeConnectionState(addr string, state tls.ConnectionState, details *ConnectionDetails) {
 	details.TLSVersion = state.Version
 	details.CipherSuite = state.CipherSuite
 	details.HandshakeComplete = state.HandshakeComplete
+	details.NegotiatedGroup = uint16(state.CurveID)
+	details.ECHAccepted = state.ECHAccepted
+	details.Resumed = state.DidResume
+	details.Protocol = state.NegotiatedProtocol
 	
 	if len(state.PeerCertificates) > 0 {
 		details.PeerCertificates = make([][]byte, len(state.PeerCertificates))
@@ -126,7 +367,34 @@ func (td *TrackedDialer) updateConnectionDetails(addr string, conn *tls.Conn, de
 func (t *TLSTracker) StoreConnection(addr string, details *ConnectionDetails) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	now := time.Now()
 	t.connections[addr] = details
+	if element, ok := t.positions[addr]; ok {
+		t.order.Remove(element)
+	}
+	t.positions[addr] = t.order.PushBack(trackedEntry{addr: addr, stored: now})
+	t.prune(now)
+}
+
+func (t *TLSTracker) SetRetention(maxEntries int, maxAge time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxEntries = maxEntries
+	t.maxAge = maxAge
+	t.prune(time.Now())
+}
+
+func (t *TLSTracker) prune(now time.Time) {
+	for element := t.order.Front(); element != nil; element = t.order.Front() {
+		entry := element.Value.(trackedEntry)
+		expired := t.maxAge > 0 && now.Sub(entry.stored) > t.maxAge
+		if !expired && (t.maxEntries <= 0 || t.order.Len() <= t.maxEntries) {
+			return
+		}
+		t.order.Remove(element)
+		delete(t.positions, entry.addr)
+		delete(t.connections, entry.addr)
+	}
 }
 
 func (t *TLSTracker) GetConnection(addr string) (*ConnectionDetails, bool) {
@@ -151,12 +419,17 @@ func (t *TLSTracker) ClearConnections() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.connections = make(map[string]*ConnectionDetails)
+	t.order.Init()
+	t.positions = make(map[string]*list.Element)
 }
 
 func GenerateFingerprintData(profile interface{}, details *ConnectionDetails) *fingerprint.Data {
 	if details == nil {
 		return generateFallbackFingerprint(profile)
 	}
+	if details.Cleartext {
+		return generateCleartextFingerprint(details)
+	}
 	
 	p := profile.(interface{ GetJA3() string })
 	tlsVersion, cipherSuites, extensions, supportedGroups, _, err := fingerprint.ParseJA3(p.GetJA3())
@@ -172,6 +445,7 @@ func GenerateFingerprintData(profile interface{}, details *ConnectionDetails) *f
 		JA3Hash:              fingerprint.GenerateJA3Hash(p.GetJA3()),
 		ClientRandom:         hex.EncodeToString(details.ClientRandom),
 		SessionID:            hex.EncodeToString(details.SessionID),
+		Protocol:             details.Protocol,
 	}
 	
 	fp.JA4 = fingerprint.GenerateJA4(details.TLSVersion, cipherSuites, extensions, supportedGroups, details.ALPNProtocols)
@@ -202,21 +476,41 @@ func GenerateFingerprintData(profile interface{}, details *ConnectionDetails) *f
 		fp.SignatureAlgorithms[i] = getSignatureAlgorithmName(alg)
 	}
 	
-	if len(details.HTTP2Settings) > 0 {
-		fp.HTTP2 = &fingerprint.HTTP2Data{
-			Settings:         details.HTTP2Settings,
-			WindowUpdate:     details.HTTP2WindowUpdate,
-			HeaderPriority:   details.HTTP2Priority,
-			SentFrames:       details.HTTP2Frames,
-			ConnectionPreface: "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n",
+	if details.Protocol == "h3" {
+		fp.HTTP3 = &fingerprint.HTTP3Data{
+			ALPN:                details.Protocol,
+			TransportParameters: details.QUICTransportParameters,
 		}
-		fp.AkamaiFP = generateAkamaiFingerprint(details.HTTP2Settings, details.HTTP2WindowUpdate, details.HTTP2Priority)
-		fp.AkamaiFPHash = fingerprint.GenerateJA3Hash(fp.AkamaiFP)
+	} else if len(details.HTTP2Settings) > 0 {
+		applyHTTP2Fingerprint(fp, details)
 	}
 	
 	return fp
 }
 
+func generateCleartextFingerprint(details *ConnectionDetails) *fingerprint.Data {
+	fp := &fingerprint.Data{
+		Protocol:  details.Protocol,
+		Cleartext: true,
+	}
+	if details.Protocol == "h2c" && len(details.HTTP2Settings) > 0 {
+		applyHTTP2Fingerprint(fp, details)
+	}
+	return fp
+}
+
+func applyHTTP2Fingerprint(fp *fingerprint.Data, details *ConnectionDetails) {
+	fp.HTTP2 = &fingerprint.HTTP2Data{
+		Settings:         details.HTTP2Settings,
+		WindowUpdate:     details.HTTP2WindowUpdate,
+		HeaderPriority:   details.HTTP2Priority,
+		SentFrames:       details.HTTP2Frames,
+		ConnectionPreface: "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n",
+	}
+	fp.AkamaiFP = generateAkamaiFingerprint(details.HTTP2Settings, details.HTTP2WindowUpdate, details.HTTP2Priority)
+	fp.AkamaiFPHash = fingerprint.GenerateJA3Hash(fp.AkamaiFP)
+}
+
 func generateFallbackFingerprint(profile interface{}) *fingerprint.Data {
 	p := profile.(interface{ GetJA3() string })
 	return &fingerprint.Data{
@@ -238,6 +532,10 @@ func getSupportedGroupName(group uint16) string {
 		0x0018: "secp384r1",
 		0x0019: "secp521r1",
 		0x001e: "x448",
+		0x0100: "ffdhe2048",
+		0x0101: "ffdhe3072",
+		0x11ec: "X25519MLKEM768",
+		0x6399: "X25519Kyber768Draft00",
 	}
 	if name, exists := groups[group]; exists {
 		return name
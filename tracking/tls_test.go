@@ -0,0 +1,35 @@
+package tracking
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTrackerRetention(t *testing.T) {
+	tracker := NewTLSTracker()
+	tracker.SetRetention(3, time.Hour)
+	for i := 0; i < 5; i++ {
+		tracker.StoreConnection("host"+strconv.Itoa(i), &ConnectionDetails{})
+	}
+	tracker.StoreConnection("host2", &ConnectionDetails{})
+	tracker.StoreConnection("host5", &ConnectionDetails{})
+
+	for _, addr := range []string{"host0", "host1", "host3"} {
+		if _, ok := tracker.GetConnection(addr); ok {
+			t.Errorf("%s kept after eviction", addr)
+		}
+	}
+	for _, addr := range []string{"host4", "host2", "host5"} {
+		if _, ok := tracker.GetConnection(addr); !ok {
+			t.Errorf("%s evicted, want it kept", addr)
+		}
+	}
+
+	tracker.SetRetention(3, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	tracker.StoreConnection("host6", &ConnectionDetails{})
+	if all := tracker.GetAllConnections(); len(all) != 1 {
+		t.Errorf("tracked %d connections after expiry, want only host6", len(all))
+	}
+}
@@ -0,0 +1,121 @@
+package tracking
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+type ClientHelloFunc func(config *tls.Config) *utls.ClientHelloSpec
+
+type trackedConn struct {
+	*utls.UConn
+	group uint16
+}
+
+func (c *trackedConn) ConnectionState() tls.ConnectionState {
+	return connectionState(c.UConn.ConnectionState(), c.group)
+}
+
+func connectionState(state utls.ConnectionState, group uint16) tls.ConnectionState {
+	return tls.ConnectionState{
+		Version:                     state.Version,
+		HandshakeComplete:           state.HandshakeComplete,
+		DidResume:                   state.DidResume,
+		CipherSuite:                 state.CipherSuite,
+		CurveID:                     tls.CurveID(group),
+		NegotiatedProtocol:          state.NegotiatedProtocol,
+		NegotiatedProtocolIsMutual:  state.NegotiatedProtocolIsMutual,
+		ServerName:                  state.ServerName,
+		PeerCertificates:            state.PeerCertificates,
+		VerifiedChains:              state.VerifiedChains,
+		SignedCertificateTimestamps: state.SignedCertificateTimestamps,
+		OCSPResponse:                state.OCSPResponse,
+		TLSUnique:                   state.TLSUnique,
+		ECHAccepted:                 state.ECHAccepted,
+	}
+}
+
+func utlsConfig(config *tls.Config) *utls.Config {
+	converted := &utls.Config{
+		Rand:                           config.Rand,
+		Time:                           config.Time,
+		NextProtos:                     config.NextProtos,
+		ServerName:                     config.ServerName,
+		RootCAs:                        config.RootCAs,
+		InsecureSkipVerify:             config.InsecureSkipVerify,
+		VerifyPeerCertificate:          config.VerifyPeerCertificate,
+		CipherSuites:                   config.CipherSuites,
+		MinVersion:                     config.MinVersion,
+		MaxVersion:                     config.MaxVersion,
+		KeyLogWriter:                   config.KeyLogWriter,
+		EncryptedClientHelloConfigList: config.EncryptedClientHelloConfigList,
+		OmitEmptyPsk:                   true,
+	}
+
+	for _, curve := range config.CurvePreferences {
+		converted.CurvePreferences = append(converted.CurvePreferences, utls.CurveID(curve))
+	}
+	for i := range config.Certificates {
+		converted.Certificates = append(converted.Certificates, *utlsCertificate(&config.Certificates[i]))
+	}
+
+	if config.GetClientCertificate != nil {
+		converted.GetClientCertificate = func(cri *utls.CertificateRequestInfo) (*utls.Certificate, error) {
+			info := &tls.CertificateRequestInfo{
+				AcceptableCAs: cri.AcceptableCAs,
+				Version:       cri.Version,
+			}
+			for _, scheme := range cri.SignatureSchemes {
+				info.SignatureSchemes = append(info.SignatureSchemes, tls.SignatureScheme(scheme))
+			}
+			cert, err := config.GetClientCertificate(info)
+			if err != nil || cert == nil {
+				return nil, err
+			}
+			return utlsCertificate(cert), nil
+		}
+	}
+
+	return converted
+}
+
+func utlsCertificate(cert *tls.Certificate) *utls.Certificate {
+	converted := &utls.Certificate{
+		Certificate:                 cert.Certificate,
+		PrivateKey:                  cert.PrivateKey,
+		OCSPStaple:                  cert.OCSPStaple,
+		SignedCertificateTimestamps: cert.SignedCertificateTimestamps,
+		Leaf:                        cert.Leaf,
+	}
+	for _, scheme := range cert.SupportedSignatureAlgorithms {
+		converted.SupportedSignatureAlgorithms = append(converted.SupportedSignatureAlgorithms, utls.SignatureScheme(scheme))
+	}
+	return converted
+}
+
+func convertError(err error) error {
+	var echErr *utls.ECHRejectionError
+	if errors.As(err, &echErr) {
+		return &tls.ECHRejectionError{RetryConfigList: echErr.RetryConfigList}
+	}
+	var verifyErr *utls.CertificateVerificationError
+	if errors.As(err, &verifyErr) {
+		return &tls.CertificateVerificationError{UnverifiedCertificates: verifyErr.UnverifiedCertificates, Err: verifyErr.Err}
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "remote error" && opErr.Err != nil {
+		if alert := reflect.ValueOf(opErr.Err); alert.Kind() == reflect.Uint8 {
+			return &net.OpError{Op: opErr.Op, Net: opErr.Net, Source: opErr.Source, Addr: opErr.Addr, Err: fmt.Errorf("%w%.0w", opErr.Err, tls.AlertError(alert.Uint()))}
+		}
+	}
+	var alertErr utls.AlertError
+	if errors.As(err, &alertErr) {
+		return fmt.Errorf("%w%.0w", err, tls.AlertError(alertErr))
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package tracking
+
+import (
+	"bytes"
+	"compress/zlib"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	utls "github.com/refraction-networking/utls"
+)
+
+func TestCompressedCertificate(t *testing.T) {
+	algorithms := []struct {
+		name     string
+		id       uint16
+		compress func([]byte) []byte
+	}{
+		{"zlib", 1, func(b []byte) []byte {
+			var buf bytes.Buffer
+			w := zlib.NewWriter(&buf)
+			w.Write(b)
+			w.Close()
+			return buf.Bytes()
+		}},
+		{"brotli", 2, func(b []byte) []byte {
+			var buf bytes.Buffer
+			w := brotli.NewWriter(&buf)
+			w.Write(b)
+			w.Close()
+			return buf.Bytes()
+		}},
+		{"zstd", 3, func(b []byte) []byte {
+			encoder, _ := zstd.NewWriter(nil)
+			defer encoder.Close()
+			return encoder.EncodeAll(b, nil)
+		}},
+	}
+
+	certificate, key, roots := newTestCertificate(t)
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.name, func(t *testing.T) {
+			addr, results := startTestServer(t, testServerConfig{
+				certificate: certificate,
+				key:         key,
+				compression: algorithm.id,
+				compress:    algorithm.compress,
+			})
+
+			conn, details, err := dialTestServer(t, NewTrackedDialer(), addr, roots, &utls.UtlsCompressCertExtension{
+				Algorithms: []utls.CertCompressionAlgo{utls.CertCompressionAlgo(algorithm.id)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if result := <-results; result.err != nil {
+				t.Fatalf("server: %v", result.err)
+			}
+
+			state := conn.(*trackedConn).ConnectionState()
+			if len(state.PeerCertificates) != 1 || !bytes.Equal(state.PeerCertificates[0].Raw, certificate) {
+				t.Fatal("decompressed certificate does not match the server's")
+			}
+
+			if !slices.Equal(details.CertCompressionAlgorithms, []uint16{algorithm.id}) {
+				t.Errorf("advertised algorithms = %v, want [%d]", details.CertCompressionAlgorithms, algorithm.id)
+			}
+		})
+	}
+}
+
+func TestApplicationSettings(t *testing.T) {
+	serverSettings := []byte{0, 3, 0, 0, 0, 100}
+	clientSettings := []byte{0, 1, 0, 1, 0, 0, 0, 4, 0, 0x60, 0, 0}
+
+	certificate, key, roots := newTestCertificate(t)
+	for _, codepoint := range []uint16{17513, 17613} {
+		t.Run(strconv.Itoa(int(codepoint)), func(t *testing.T) {
+			addr, results := startTestServer(t, testServerConfig{
+				certificate: certificate,
+				key:         key,
+				protocol:    "h2",
+				alps:        codepoint,
+				settings:    serverSettings,
+			})
+
+			var alps utls.TLSExtension = &utls.ApplicationSettingsExtension{SupportedProtocols: []string{"h2"}}
+			if codepoint == 17613 {
+				alps = &utls.ApplicationSettingsExtensionNew{SupportedProtocols: []string{"h2"}}
+			}
+			dialer := NewTrackedDialer()
+			dialer.SetApplicationSettings(map[string][]byte{"h2": clientSettings})
+			conn, details, err := dialTestServer(t, dialer, addr, roots, &utls.ALPNExtension{AlpnProtocols: []string{"h2"}}, alps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			result := <-results
+			if result.err != nil {
+				t.Fatalf("server: %v", result.err)
+			}
+			if !bytes.Equal(result.clientALPS, clientSettings) {
+				t.Errorf("server received client settings %x, want %x", result.clientALPS, clientSettings)
+			}
+
+			if details.ALPSCodepoint != codepoint || !details.ALPSNegotiated {
+				t.Errorf("details codepoint %d negotiated %v, want %d negotiated", details.ALPSCodepoint, details.ALPSNegotiated, codepoint)
+			}
+			if !bytes.Equal(details.PeerApplicationSettings, serverSettings) {
+				t.Errorf("peer settings = %x, want %x", details.PeerApplicationSettings, serverSettings)
+			}
+		})
+	}
+}